@@ -9,6 +9,7 @@ import (
 type Node interface {
 	TokenLiteral() string
 	String() string
+	Pos() token.Position
 }
 
 type Statement interface {
@@ -33,6 +34,14 @@ func (p *Program) TokenLiteral() string {
 	}
 }
 
+func (p *Program) Pos() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[0].Pos()
+	}
+
+	return token.Position{}
+}
+
 func (p *Program) String() string {
 	var out bytes.Buffer
 
@@ -51,6 +60,7 @@ type PrefixExpression struct {
 
 func (pe *PrefixExpression) expressionNode()      {}
 func (pe *PrefixExpression) TokenLiteral() string { return pe.Token.Literal }
+func (pe *PrefixExpression) Pos() token.Position  { return pe.Token.Position }
 func (pe *PrefixExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("(")
@@ -69,6 +79,7 @@ type InfixExpression struct {
 
 func (ie *InfixExpression) expressionNode()      {}
 func (ie *InfixExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *InfixExpression) Pos() token.Position  { return ie.Token.Position }
 func (ie *InfixExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("(")
@@ -86,6 +97,7 @@ type BooleanLiteral struct {
 
 func (bl *BooleanLiteral) expressionNode()      {}
 func (bl *BooleanLiteral) TokenLiteral() string { return bl.Token.Literal }
+func (bl *BooleanLiteral) Pos() token.Position  { return bl.Token.Position }
 func (bl *BooleanLiteral) String() string       { return bl.Token.Literal }
 
 type IfExpression struct {
@@ -97,6 +109,7 @@ type IfExpression struct {
 
 func (ie *IfExpression) expressionNode()      {}
 func (ie *IfExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IfExpression) Pos() token.Position  { return ie.Token.Position }
 func (ie *IfExpression) String() string {
 	var out bytes.Buffer
 
@@ -120,6 +133,7 @@ type BlockStatement struct {
 
 func (bs *BlockStatement) statementNode()       {}
 func (bs *BlockStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BlockStatement) Pos() token.Position  { return bs.Token.Position }
 func (bs *BlockStatement) String() string {
 	var out bytes.Buffer
 
@@ -133,29 +147,44 @@ func (bs *BlockStatement) String() string {
 type FunctionLiteral struct {
 	Token      token.Token
 	Parameters []*Identifier
+	Defaults   []Expression // the default value of each parameter, nil where there is none
+	Variadic   bool         // whether the last parameter is a `...rest` collecting any extra arguments
 	Body       *BlockStatement
 }
 
 func (fl *FunctionLiteral) expressionNode()      {}
 func (fl *FunctionLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FunctionLiteral) Pos() token.Position  { return fl.Token.Position }
 func (fl *FunctionLiteral) String() string {
 	var out bytes.Buffer
 
-	params := []string{}
-
-	for _, p := range fl.Parameters {
-		params = append(params, p.String())
-	}
-
 	out.WriteString(fl.TokenLiteral())
 	out.WriteString("(")
-	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(FormatParameters(fl.Parameters, fl.Defaults, fl.Variadic))
 	out.WriteString(") ")
 	out.WriteString(fl.Body.String())
 
 	return out.String()
 }
 
+// FormatParameters writes out a parameter list as it appears in source.
+func FormatParameters(parameters []*Identifier, defaults []Expression, variadic bool) string {
+	params := []string{}
+
+	for i, p := range parameters {
+		param := p.String()
+		if i < len(defaults) && defaults[i] != nil {
+			param += " = " + defaults[i].String()
+		}
+		if variadic && i == len(parameters)-1 {
+			param = "..." + param
+		}
+		params = append(params, param)
+	}
+
+	return strings.Join(params, ", ")
+}
+
 type CallExpression struct {
 	Token     token.Token
 	Function  Expression
@@ -164,6 +193,7 @@ type CallExpression struct {
 
 func (ce *CallExpression) expressionNode()      {}
 func (ce *CallExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *CallExpression) Pos() token.Position  { return ce.Token.Position }
 func (ce *CallExpression) String() string {
 	var out bytes.Buffer
 	args := []string{}
@@ -184,8 +214,36 @@ type StringLiteral struct {
 
 func (sl *StringLiteral) expressionNode()      {}
 func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
+func (sl *StringLiteral) Pos() token.Position  { return sl.Token.Position }
 func (sl *StringLiteral) String() string       { return sl.Token.Literal }
 
+// TemplateLiteral is an interpolated string like "x is {x}". Its Parts are
+// the StringLiterals between interpolations and the interpolated expressions,
+// in order.
+type TemplateLiteral struct {
+	Token token.Token // the TEMPLATE_HEAD token
+	Parts []Expression
+}
+
+func (tl *TemplateLiteral) expressionNode()      {}
+func (tl *TemplateLiteral) TokenLiteral() string { return tl.Token.Literal }
+func (tl *TemplateLiteral) Pos() token.Position  { return tl.Token.Position }
+func (tl *TemplateLiteral) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("\"")
+	for _, part := range tl.Parts {
+		if text, ok := part.(*StringLiteral); ok && text.Token.Type != token.STRING {
+			out.WriteString(text.Value)
+		} else {
+			out.WriteString("{" + part.String() + "}")
+		}
+	}
+	out.WriteString("\"")
+
+	return out.String()
+}
+
 type ArrayLiteral struct {
 	Token    token.Token
 	Elements []Expression
@@ -193,6 +251,7 @@ type ArrayLiteral struct {
 
 func (a *ArrayLiteral) expressionNode()      {}
 func (a *ArrayLiteral) TokenLiteral() string { return a.Token.Literal }
+func (a *ArrayLiteral) Pos() token.Position  { return a.Token.Position }
 func (a *ArrayLiteral) String() string {
 	var out bytes.Buffer
 
@@ -214,6 +273,7 @@ type IndexExpression struct {
 
 func (ie *IndexExpression) expressionNode()      {}
 func (ie *IndexExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IndexExpression) Pos() token.Position  { return ie.Token.Position }
 func (ie *IndexExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("")
@@ -231,6 +291,7 @@ type MapLiteral struct {
 
 func (hl *MapLiteral) expressionNode()      {}
 func (hl *MapLiteral) TokenLiteral() string { return hl.Token.Literal }
+func (hl *MapLiteral) Pos() token.Position  { return hl.Token.Position }
 func (hl *MapLiteral) String() string {
 	var out bytes.Buffer
 	pairs := []string{}
@@ -242,3 +303,57 @@ func (hl *MapLiteral) String() string {
 	out.WriteString("}")
 	return out.String()
 }
+
+type MemberExpression struct {
+	Token    token.Token
+	Object   Expression
+	Property *Identifier
+}
+
+func (me *MemberExpression) expressionNode()      {}
+func (me *MemberExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MemberExpression) Pos() token.Position  { return me.Token.Position }
+func (me *MemberExpression) String() string {
+	return me.Object.String() + "." + me.Property.String()
+}
+
+type ImportExpression struct {
+	Token token.Token
+	Path  string
+}
+
+func (ie *ImportExpression) expressionNode()      {}
+func (ie *ImportExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *ImportExpression) Pos() token.Position  { return ie.Token.Position }
+func (ie *ImportExpression) String() string {
+	return ie.TokenLiteral() + " \"" + ie.Path + "\""
+}
+
+// AssignmentExpression is a compound assignment such as `x += 1`.
+type AssignmentExpression struct {
+	Token    token.Token
+	Name     *Identifier
+	Operator string
+	Value    Expression
+}
+
+func (ae *AssignmentExpression) expressionNode()      {}
+func (ae *AssignmentExpression) TokenLiteral() string { return ae.Token.Literal }
+func (ae *AssignmentExpression) Pos() token.Position  { return ae.Token.Position }
+func (ae *AssignmentExpression) String() string {
+	return "(" + ae.Name.String() + " " + ae.Operator + " " + ae.Value.String() + ")"
+}
+
+// IncrementExpression is `++x` or `--x`.
+type IncrementExpression struct {
+	Token    token.Token
+	Name     *Identifier
+	Operator string
+}
+
+func (ie *IncrementExpression) expressionNode()      {}
+func (ie *IncrementExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IncrementExpression) Pos() token.Position  { return ie.Token.Position }
+func (ie *IncrementExpression) String() string {
+	return "(" + ie.Operator + ie.Name.String() + ")"
+}
@@ -0,0 +1,13 @@
+package ast
+
+import "sepia/token"
+
+type FloatLiteral struct {
+	Token token.Token
+	Value float64
+}
+
+func (fl *FloatLiteral) expressionNode()      {}
+func (fl *FloatLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FloatLiteral) Pos() token.Position  { return fl.Token.Position }
+func (fl *FloatLiteral) String() string       { return fl.Token.Literal }
@@ -5,8 +5,23 @@ import "sepia/token"
 type Identifier struct {
 	Token token.Token
 	Value string
+	Type  *Identifier // an optional annotation, as in `n: INTEGER`, on parameters and bindings
 }
 
 func (i *Identifier) expressionNode()      {}
 func (i *Identifier) TokenLiteral() string { return i.Token.Literal }
-func (i *Identifier) String() string       { return i.Value }
+func (i *Identifier) Pos() token.Position  { return i.Token.Position }
+func (i *Identifier) String() string {
+	if i.Type != nil {
+		return i.Value + ": " + i.Type.Value
+	}
+	return i.Value
+}
+
+// TypeName is the name of the type i is annotated with, or "" if it has none.
+func (i *Identifier) TypeName() string {
+	if i.Type == nil {
+		return ""
+	}
+	return i.Type.Value
+}
@@ -1,12 +1,17 @@
 package ast
 
-import "sepia/token"
+import (
+	"math/big"
+	"sepia/token"
+)
 
 type IntegerLiteral struct {
 	Token token.Token
 	Value int64
+	Big   *big.Int // set instead of Value when the literal does not fit in int64
 }
 
 func (il *IntegerLiteral) expressionNode()      {}
 func (il *IntegerLiteral) TokenLiteral() string { return il.Token.Literal }
+func (il *IntegerLiteral) Pos() token.Position  { return il.Token.Position }
 func (il *IntegerLiteral) String() string       { return il.Token.Literal }
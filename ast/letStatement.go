@@ -13,6 +13,7 @@ type ValueStatement struct {
 
 func (ls *ValueStatement) statementNode()       {}
 func (ls *ValueStatement) TokenLiteral() string { return ls.Token.Literal }
+func (ls *ValueStatement) Pos() token.Position  { return ls.Token.Position }
 func (ls *ValueStatement) String() string {
 	var out bytes.Buffer
 
@@ -37,6 +38,7 @@ type UpdateStatement struct {
 
 func (ls *UpdateStatement) statementNode()       {}
 func (ls *UpdateStatement) TokenLiteral() string { return ls.Token.Literal }
+func (ls *UpdateStatement) Pos() token.Position  { return ls.Token.Position }
 func (ls *UpdateStatement) String() string {
 	var out bytes.Buffer
 
@@ -52,3 +54,28 @@ func (ls *UpdateStatement) String() string {
 
 	return out.String()
 }
+
+type ConstantStatement struct {
+	Token token.Token
+	Name  *Identifier
+	Value Expression
+}
+
+func (cs *ConstantStatement) statementNode()       {}
+func (cs *ConstantStatement) TokenLiteral() string { return cs.Token.Literal }
+func (cs *ConstantStatement) Pos() token.Position  { return cs.Token.Position }
+func (cs *ConstantStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(cs.TokenLiteral() + " ")
+	out.WriteString(cs.Name.String())
+	out.WriteString(" = ")
+
+	if cs.Value != nil {
+		out.WriteString(cs.Value.String())
+	}
+
+	out.WriteString(";")
+
+	return out.String()
+}
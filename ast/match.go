@@ -0,0 +1,191 @@
+package ast
+
+import (
+	"bytes"
+	"sepia/token"
+	"strings"
+)
+
+// MatchExpression compares a value against the pattern of each arm in turn,
+// evaluating the body of the first arm that matches:
+//
+//	match (arr) ->
+//	    [] -> 0 end
+//	    [head, ...tail] if (head > 0) -> head + sum(tail) end
+//	    _ -> sum(rest(arr)) end
+//	end
+type MatchExpression struct {
+	Token   token.Token // the 'match' token
+	Subject Expression
+	Arms    []*MatchArm
+}
+
+func (me *MatchExpression) expressionNode()      {}
+func (me *MatchExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MatchExpression) Pos() token.Position  { return me.Token.Position }
+func (me *MatchExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("match (")
+	out.WriteString(me.Subject.String())
+	out.WriteString(") -> ")
+	for _, arm := range me.Arms {
+		out.WriteString(arm.String())
+		out.WriteString(" ")
+	}
+	out.WriteString("end")
+
+	return out.String()
+}
+
+// MatchArm is one `pattern if (guard) -> body end` of a match expression.
+type MatchArm struct {
+	Pattern Pattern
+	Guard   Expression // nil if the arm has no guard
+	Body    *BlockStatement
+}
+
+func (ma *MatchArm) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(ma.Pattern.String())
+	if ma.Guard != nil {
+		out.WriteString(" if (")
+		out.WriteString(ma.Guard.String())
+		out.WriteString(")")
+	}
+	out.WriteString(" -> ")
+	out.WriteString(ma.Body.String())
+	out.WriteString(" end")
+
+	return out.String()
+}
+
+// Pattern is the shape a value is compared against in a match arm.
+type Pattern interface {
+	Node
+	patternNode()
+}
+
+// LiteralPattern matches a value equal to a number, string or boolean
+// literal. Numbers compare like `==`, so `1` matches 1.0; strings and booleans
+// only match values of their own type.
+type LiteralPattern struct {
+	Value Expression
+}
+
+func (lp *LiteralPattern) patternNode()         {}
+func (lp *LiteralPattern) TokenLiteral() string { return lp.Value.TokenLiteral() }
+func (lp *LiteralPattern) Pos() token.Position  { return lp.Value.Pos() }
+func (lp *LiteralPattern) String() string       { return lp.Value.String() }
+
+// BindingPattern matches any value and binds it to a name, unless the name
+// is `_`. A type annotation (`n: INTEGER`) only matches values of that type.
+type BindingPattern struct {
+	Name *Identifier
+}
+
+func (bp *BindingPattern) patternNode()         {}
+func (bp *BindingPattern) TokenLiteral() string { return bp.Name.TokenLiteral() }
+func (bp *BindingPattern) Pos() token.Position  { return bp.Name.Pos() }
+func (bp *BindingPattern) String() string       { return bp.Name.String() }
+
+// ArrayPattern matches an array element by element. Without a `...rest` it
+// only matches arrays of the same length; with one, the rest are bound to it.
+type ArrayPattern struct {
+	Token    token.Token // the '[' token
+	Elements []Pattern
+	Rest     *Identifier // nil if there is no `...rest`
+}
+
+func (ap *ArrayPattern) patternNode()         {}
+func (ap *ArrayPattern) TokenLiteral() string { return ap.Token.Literal }
+func (ap *ArrayPattern) Pos() token.Position  { return ap.Token.Position }
+func (ap *ArrayPattern) String() string {
+	elements := []string{}
+	for _, el := range ap.Elements {
+		elements = append(elements, el.String())
+	}
+	if ap.Rest != nil {
+		elements = append(elements, "..."+ap.Rest.String())
+	}
+
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+// MapPattern matches a map having each of its keys, with values matching the
+// patterns paired with them. Other keys are ignored.
+type MapPattern struct {
+	Token  token.Token // the '{' token
+	Keys   []Expression
+	Values []Pattern
+}
+
+func (mp *MapPattern) patternNode()         {}
+func (mp *MapPattern) TokenLiteral() string { return mp.Token.Literal }
+func (mp *MapPattern) Pos() token.Position  { return mp.Token.Position }
+func (mp *MapPattern) String() string {
+	pairs := []string{}
+	for i, key := range mp.Keys {
+		pairs = append(pairs, key.String()+": "+mp.Values[i].String())
+	}
+
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+// StructPattern matches an instance of the structure Name whose fields match
+// the patterns paired with them. Fields given without a pattern are bound to
+// their own names:
+//
+//	Point { x: 0, y }
+type StructPattern struct {
+	Name   *Identifier
+	Fields []*Identifier
+	Values []Pattern
+}
+
+func (sp *StructPattern) patternNode()         {}
+func (sp *StructPattern) TokenLiteral() string { return sp.Name.TokenLiteral() }
+func (sp *StructPattern) Pos() token.Position  { return sp.Name.Pos() }
+func (sp *StructPattern) String() string {
+	fields := []string{}
+	for i, field := range sp.Fields {
+		fields = append(fields, field.String()+": "+sp.Values[i].String())
+	}
+
+	return sp.Name.String() + " { " + strings.Join(fields, ", ") + " }"
+}
+
+// PatternBindings lists the names a pattern binds, in the order it binds them.
+func PatternBindings(pattern Pattern) []*Identifier {
+	switch pattern := pattern.(type) {
+	case *BindingPattern:
+		if pattern.Name.Value == "_" {
+			return nil
+		}
+		return []*Identifier{pattern.Name}
+	case *ArrayPattern:
+		names := []*Identifier{}
+		for _, el := range pattern.Elements {
+			names = append(names, PatternBindings(el)...)
+		}
+		if pattern.Rest != nil && pattern.Rest.Value != "_" {
+			names = append(names, pattern.Rest)
+		}
+		return names
+	case *MapPattern:
+		names := []*Identifier{}
+		for _, value := range pattern.Values {
+			names = append(names, PatternBindings(value)...)
+		}
+		return names
+	case *StructPattern:
+		names := []*Identifier{}
+		for _, value := range pattern.Values {
+			names = append(names, PatternBindings(value)...)
+		}
+		return names
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,36 @@
+package ast
+
+import (
+	"bytes"
+	"sepia/token"
+	"strings"
+)
+
+// StructureStatement declares a named structure and its fields:
+//
+//	structure Point -> x, y end
+type StructureStatement struct {
+	Token  token.Token
+	Name   *Identifier
+	Fields []*Identifier
+}
+
+func (ss *StructureStatement) statementNode()       {}
+func (ss *StructureStatement) TokenLiteral() string { return ss.Token.Literal }
+func (ss *StructureStatement) Pos() token.Position  { return ss.Token.Position }
+func (ss *StructureStatement) String() string {
+	var out bytes.Buffer
+
+	fields := []string{}
+	for _, field := range ss.Fields {
+		fields = append(fields, field.String())
+	}
+
+	out.WriteString(ss.TokenLiteral() + " ")
+	out.WriteString(ss.Name.String())
+	out.WriteString(" -> ")
+	out.WriteString(strings.Join(fields, ", "))
+	out.WriteString(" end")
+
+	return out.String()
+}
@@ -0,0 +1,167 @@
+package compiler
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// Instructions is a flat sequence of encoded opcodes and their operands.
+type Instructions []byte
+
+// Opcode is the first byte of every instruction.
+type Opcode byte
+
+const (
+	OpConstant Opcode = iota
+	OpPop
+	OpTrue
+	OpFalse
+	OpNull
+	OpInfix
+	OpPrefix
+	OpJump
+	OpJumpNotTruthy
+	OpJumpIfSet
+	OpMatch
+	OpNoMatch
+	OpGetVar
+	OpSetVar
+	OpCheckType
+	OpUndefined
+	OpFail
+	OpArray
+	OpTemplate
+	OpMap
+	OpIndex
+	OpMember
+	OpClosure
+	OpImport
+	OpCall
+	OpTailCall
+	OpReturnValue
+)
+
+// Definition describes an opcode's name and the byte width of each operand.
+type Definition struct {
+	Name          string
+	OperandWidths []int
+}
+
+var definitions = map[Opcode]*Definition{
+	OpConstant:      {"OpConstant", []int{2}},
+	OpPop:           {"OpPop", []int{}},
+	OpTrue:          {"OpTrue", []int{}},
+	OpFalse:         {"OpFalse", []int{}},
+	OpNull:          {"OpNull", []int{}},
+	OpInfix:         {"OpInfix", []int{2}},  // constant index of the operator
+	OpPrefix:        {"OpPrefix", []int{2}}, // constant index of the operator
+	OpJump:          {"OpJump", []int{2}},
+	OpJumpNotTruthy: {"OpJumpNotTruthy", []int{2}},
+	OpJumpIfSet:     {"OpJumpIfSet", []int{2, 2}}, // target, slot of the current scope
+	OpMatch:         {"OpMatch", []int{2, 2}},     // constant index of the pattern, target if it doesn't match
+	OpNoMatch:       {"OpNoMatch", []int{}},
+	OpGetVar:        {"OpGetVar", []int{1, 2}},    // scope depth, slot
+	OpSetVar:        {"OpSetVar", []int{1, 2}},    // scope depth, slot
+	OpCheckType:     {"OpCheckType", []int{2, 2}}, // constant indexes of the type name and of what is being bound
+	OpUndefined:     {"OpUndefined", []int{2}},    // constant index of the name
+	OpFail:          {"OpFail", []int{2}},         // constant index of the error message
+	OpArray:         {"OpArray", []int{2}},
+	OpTemplate:      {"OpTemplate", []int{2}}, // number of parts to join
+	OpMap:           {"OpMap", []int{2}},
+	OpIndex:         {"OpIndex", []int{}},
+	OpMember:        {"OpMember", []int{2}}, // constant index of the field name
+	OpClosure:       {"OpClosure", []int{2}},
+	OpImport:        {"OpImport", []int{2}}, // constant index of the compiled module
+	OpCall:          {"OpCall", []int{1}},
+	OpTailCall:      {"OpTailCall", []int{1}},
+	OpReturnValue:   {"OpReturnValue", []int{}},
+}
+
+// Lookup finds the definition of an opcode.
+func Lookup(op byte) (*Definition, error) {
+	def, ok := definitions[Opcode(op)]
+	if !ok {
+		return nil, fmt.Errorf("opcode %d undefined", op)
+	}
+
+	return def, nil
+}
+
+// Make encodes an instruction.
+func Make(op Opcode, operands ...int) []byte {
+	def, ok := definitions[op]
+	if !ok {
+		return []byte{}
+	}
+
+	length := 1
+	for _, w := range def.OperandWidths {
+		length += w
+	}
+
+	instruction := make([]byte, length)
+	instruction[0] = byte(op)
+
+	offset := 1
+	for i, o := range operands {
+		width := def.OperandWidths[i]
+		switch width {
+		case 2:
+			binary.BigEndian.PutUint16(instruction[offset:], uint16(o))
+		case 1:
+			instruction[offset] = byte(o)
+		}
+		offset += width
+	}
+
+	return instruction
+}
+
+// ReadOperands decodes the operands following an opcode, returning them and
+// how many bytes they took up.
+func ReadOperands(def *Definition, ins Instructions) ([]int, int) {
+	operands := make([]int, len(def.OperandWidths))
+	offset := 0
+
+	for i, width := range def.OperandWidths {
+		switch width {
+		case 2:
+			operands[i] = int(ReadUint16(ins[offset:]))
+		case 1:
+			operands[i] = int(ins[offset])
+		}
+		offset += width
+	}
+
+	return operands, offset
+}
+
+func ReadUint16(ins Instructions) uint16 {
+	return binary.BigEndian.Uint16(ins)
+}
+
+// String disassembles the instructions, one per line.
+func (ins Instructions) String() string {
+	var out bytes.Buffer
+
+	for i := 0; i < len(ins); {
+		def, err := Lookup(ins[i])
+		if err != nil {
+			fmt.Fprintf(&out, "ERROR: %s\n", err)
+			i++
+			continue
+		}
+
+		operands, read := ReadOperands(def, ins[i+1:])
+		fmt.Fprintf(&out, "%04d %s", i, def.Name)
+		for _, o := range operands {
+			fmt.Fprintf(&out, " %d", o)
+		}
+		out.WriteString("\n")
+
+		i += 1 + read
+	}
+
+	return out.String()
+}
@@ -0,0 +1,814 @@
+package compiler
+
+import (
+	"fmt"
+	"path/filepath"
+	"sepia/ast"
+	"sepia/evaluator"
+	"sepia/objects"
+	"sepia/token"
+	"strings"
+)
+
+// CompiledFunction is a function body lowered to bytecode. The program itself
+// is compiled into one too.
+type CompiledFunction struct {
+	Name          string
+	Instructions  Instructions
+	Positions     []token.Position // source position of every instruction byte
+	SlotNames     []string         // names of the locals, by slot
+	NumParameters int
+	NumRequired   int  // how many parameters come before the first with a default value
+	Variadic      bool // whether the last parameter collects any further arguments into an array
+
+	ParameterTypes []string // type annotations of the parameters, "" where there is none
+}
+
+func (cf *CompiledFunction) Type() objects.ObjectType { return objects.FUNCTION_OBJ }
+func (cf *CompiledFunction) Inspect() string          { return "compiled function " + cf.Name }
+
+// Pattern is the pattern of a match arm, kept among the constants for OpMatch.
+type Pattern struct {
+	Pattern ast.Pattern
+}
+
+func (p *Pattern) Type() objects.ObjectType { return "PATTERN" }
+func (p *Pattern) Inspect() string          { return "pattern " + p.Pattern.String() }
+
+// Bytecode is everything the vm needs to run a program.
+type Bytecode struct {
+	Main      *CompiledFunction
+	Constants []objects.Object
+}
+
+type compilationScope struct {
+	function *CompiledFunction
+	symbols  *SymbolTable
+}
+
+type Compiler struct {
+	constants []objects.Object
+	names     map[string]int           // constant indexes of the strings added by addName
+	builtins  map[*objects.Builtin]int // constant indexes of the builtins referred to
+	scopes    []*compilationScope
+	main      *CompiledFunction
+
+	modules     map[string]int // constant indexes of the compiled modules, by absolute path
+	importStack []string       // the files being compiled, the program's own first, to detect import cycles
+
+	// err is the first instruction whose operands didn't fit, if any. It
+	// stops compilation at the end of the statement being compiled.
+	err error
+
+	// position is the source position attached to emitted instructions.
+	position token.Position
+}
+
+// Error is a problem found while compiling, such as updating a constant.
+type Error struct {
+	Position token.Position
+	Message  string
+}
+
+func (e *Error) Error() string {
+	if e.Position.IsValid() {
+		return e.Position.String() + ": " + e.Message
+	}
+	return e.Message
+}
+
+func New() *Compiler {
+	return &Compiler{names: map[string]int{}, builtins: map[*objects.Builtin]int{}, modules: map[string]int{}}
+}
+
+// Compile lowers program into bytecode, retrievable with Bytecode.
+func (c *Compiler) Compile(program *ast.Program) error {
+	if file := program.Pos().File; file != "" {
+		if path, err := filepath.Abs(file); err == nil {
+			c.importStack = append(c.importStack, path)
+		}
+	}
+
+	c.enterScope(NewSymbolTable(), "<main>")
+	c.hoist(program.Statements)
+
+	if err := c.compileBlock(program.Statements, false); err != nil {
+		return err
+	}
+	c.emit(OpReturnValue)
+
+	c.main = c.leaveScope()
+	return c.err
+}
+
+func (c *Compiler) Bytecode() *Bytecode {
+	return &Bytecode{Main: c.main, Constants: c.constants}
+}
+
+//
+// UTILITY/SCOPES
+//
+
+func (c *Compiler) enterScope(symbols *SymbolTable, name string) {
+	c.scopes = append(c.scopes, &compilationScope{
+		function: &CompiledFunction{Name: name},
+		symbols:  symbols,
+	})
+}
+
+func (c *Compiler) leaveScope() *CompiledFunction {
+	scope := c.currentScope()
+	c.scopes = c.scopes[:len(c.scopes)-1]
+
+	scope.function.SlotNames = make([]string, scope.symbols.NumDefinitions())
+	for name, symbol := range scope.symbols.store {
+		scope.function.SlotNames[symbol.Index] = name
+	}
+
+	return scope.function
+}
+
+func (c *Compiler) currentScope() *compilationScope {
+	return c.scopes[len(c.scopes)-1]
+}
+
+func (c *Compiler) symbols() *SymbolTable {
+	return c.currentScope().symbols
+}
+
+// inFunction reports whether we are compiling a function body rather than the program.
+func (c *Compiler) inFunction() bool {
+	return len(c.scopes) > 1
+}
+
+// hoist defines every binding made in a scope before compiling it, so that
+// functions can refer to names bound after them, as they can in the evaluator.
+func (c *Compiler) hoist(statements []ast.Statement) {
+	for _, statement := range statements {
+		switch statement := statement.(type) {
+		case *ast.ValueStatement:
+			c.symbols().Define(statement.Name.Value)
+			c.hoistExpression(statement.Value)
+		case *ast.ConstantStatement:
+			c.symbols().Define(statement.Name.Value)
+			c.hoistExpression(statement.Value)
+		case *ast.StructureStatement:
+			c.symbols().Define(statement.Name.Value)
+		case *ast.UpdateStatement:
+			c.hoistExpression(statement.Value)
+		case *ast.ReturnStatement:
+			c.hoistExpression(statement.ReturnValue)
+		case *ast.ExpressionStatement:
+			c.hoistExpression(statement.Expression)
+		}
+	}
+}
+
+// hoistExpression finds `if` blocks (which share the enclosing scope) inside an expression.
+func (c *Compiler) hoistExpression(expression ast.Expression) {
+	switch expression := expression.(type) {
+	case *ast.IfExpression:
+		c.hoistExpression(expression.Condition)
+		c.hoist(expression.Consequence.Statements)
+		if expression.Alternative != nil {
+			c.hoist(expression.Alternative.Statements)
+		}
+	case *ast.MatchExpression:
+		c.hoistExpression(expression.Subject)
+		for _, arm := range expression.Arms {
+			for _, name := range ast.PatternBindings(arm.Pattern) {
+				c.symbols().Define(name.Value)
+			}
+			c.hoistExpression(arm.Guard)
+			c.hoist(arm.Body.Statements)
+		}
+	case *ast.InfixExpression:
+		c.hoistExpression(expression.Left)
+		c.hoistExpression(expression.Right)
+	case *ast.PrefixExpression:
+		c.hoistExpression(expression.Right)
+	case *ast.CallExpression:
+		c.hoistExpression(expression.Function)
+		for _, arg := range expression.Arguments {
+			c.hoistExpression(arg)
+		}
+	case *ast.ArrayLiteral:
+		for _, el := range expression.Elements {
+			c.hoistExpression(el)
+		}
+	case *ast.TemplateLiteral:
+		for _, part := range expression.Parts {
+			c.hoistExpression(part)
+		}
+	case *ast.IndexExpression:
+		c.hoistExpression(expression.Left)
+		c.hoistExpression(expression.Index)
+	case *ast.MemberExpression:
+		c.hoistExpression(expression.Object)
+	case *ast.MapLiteral:
+		for key, value := range expression.Pairs {
+			c.hoistExpression(key)
+			c.hoistExpression(value)
+		}
+	case *ast.AssignmentExpression:
+		c.hoistExpression(expression.Value)
+	}
+}
+
+//
+// UTILITY/EMISSION
+//
+
+func (c *Compiler) emit(op Opcode, operands ...int) int {
+	c.checkOperands(op, operands)
+	instruction := Make(op, operands...)
+	function := c.currentScope().function
+
+	position := len(function.Instructions)
+	function.Instructions = append(function.Instructions, instruction...)
+	for range instruction {
+		function.Positions = append(function.Positions, c.position)
+	}
+
+	return position
+}
+
+func (c *Compiler) changeOperands(position int, operands ...int) {
+	c.checkOperands(Opcode(c.currentScope().function.Instructions[position]), operands)
+	function := c.currentScope().function
+	op := Opcode(function.Instructions[position])
+	copy(function.Instructions[position:], Make(op, operands...))
+}
+
+// checkOperands records an error if an operand is too big for the bytes
+// the instruction has for it, which Make would silently truncate.
+func (c *Compiler) checkOperands(op Opcode, operands []int) {
+	if c.err != nil {
+		return
+	}
+
+	for i, width := range definitions[op].OperandWidths {
+		if i >= len(operands) || operands[i] < 1<<(8*uint(width)) {
+			continue
+		}
+
+		switch op {
+		case OpCall, OpTailCall:
+			c.err = &Error{Position: c.position, Message: fmt.Sprintf("too many arguments in one call: %d, the vm allows at most %d", operands[i], 1<<(8*uint(width))-1)}
+		default:
+			c.err = &Error{Position: c.position, Message: fmt.Sprintf("program too large for the vm: %s needs an operand of %d, at most %d fits", definitions[op].Name, operands[i], 1<<(8*uint(width))-1)}
+		}
+		return
+	}
+}
+
+func (c *Compiler) addConstant(obj objects.Object) int {
+	c.constants = append(c.constants, obj)
+	return len(c.constants) - 1
+}
+
+// addName adds a string constant used as an operand, such as an operator or
+// a name, reusing the constant if the same string was added before.
+func (c *Compiler) addName(name string) int {
+	if index, ok := c.names[name]; ok {
+		return index
+	}
+	index := c.addConstant(&objects.String{Value: name})
+	c.names[name] = index
+	return index
+}
+
+// at makes node the source position of instructions emitted until the returned func is called.
+func (c *Compiler) at(node ast.Node) func() {
+	previous := c.position
+	c.position = node.Pos()
+	return func() { c.position = previous }
+}
+
+func (c *Compiler) errorf(node ast.Node, format string, a ...interface{}) error {
+	return &Error{Position: node.Pos(), Message: fmt.Sprintf(format, a...)}
+}
+
+//
+// COMPILING/STATEMENTS
+//
+
+// compileBlock compiles statements so that they leave exactly one value, the
+// value of the block, on the stack.
+func (c *Compiler) compileBlock(statements []ast.Statement, isTail bool) error {
+	if len(statements) == 0 {
+		c.emit(OpNull)
+		return nil
+	}
+
+	for idx, statement := range statements {
+		last := idx == len(statements)-1
+
+		if expStatement, ok := statement.(*ast.ExpressionStatement); ok {
+			if err := c.compileExpression(expStatement.Expression, isTail && last); err != nil {
+				return err
+			}
+			if !last {
+				c.emit(OpPop)
+			}
+			continue
+		}
+
+		if err := c.compileStatement(statement); err != nil {
+			return err
+		}
+		if c.err != nil {
+			return c.err
+		}
+		if last {
+			c.emit(OpNull)
+		}
+	}
+
+	return nil
+}
+
+// compileStatement compiles a statement that leaves nothing on the stack.
+func (c *Compiler) compileStatement(statement ast.Statement) error {
+	defer c.at(statement)()
+
+	switch statement := statement.(type) {
+	case *ast.ValueStatement:
+		symbol := c.symbols().Define(statement.Name.Value)
+		if symbol.Constant {
+			return c.compileRedefinition(statement.Name, statement.Value)
+		}
+		if err := c.compileBinding(statement.Value, symbol.Name); err != nil {
+			return err
+		}
+		symbol.Type = statement.Name.TypeName()
+		c.emitCheckType(symbol)
+		c.emit(OpSetVar, 0, symbol.Index)
+	case *ast.ConstantStatement:
+		symbol := c.symbols().Define(statement.Name.Value)
+		if symbol.Constant {
+			return c.compileRedefinition(statement.Name, statement.Value)
+		}
+		if err := c.compileBinding(statement.Value, symbol.Name); err != nil {
+			return err
+		}
+		symbol.Type = statement.Name.TypeName()
+		c.emitCheckType(symbol)
+		c.emit(OpSetVar, 0, symbol.Index)
+		symbol.Constant = true
+	case *ast.StructureStatement:
+		symbol := c.symbols().Define(statement.Name.Value)
+		structure := evaluator.NewStructure(statement)
+		if err, ok := structure.(*objects.Error); ok {
+			c.emitFail("%s", err.Message)
+			return nil
+		}
+		if symbol.Constant {
+			c.emitFail("Cannot redefine constant `%s`.", symbol.Name)
+			return nil
+		}
+		c.emit(OpConstant, c.addConstant(structure))
+		c.emit(OpSetVar, 0, symbol.Index)
+		symbol.Constant = true
+	case *ast.UpdateStatement:
+		symbol, depth, ok := c.symbols().Resolve(statement.Name.Value)
+		if err := c.compileExpression(statement.Value, false); err != nil {
+			return err
+		}
+		if !ok {
+			c.emitFail("Could not find identitier `%s` in program.", statement.Name.Value)
+			return nil
+		}
+		if symbol.Constant {
+			c.emitFail("Cannot update constant `%s`.", symbol.Name)
+			return nil
+		}
+		c.emitCheckType(symbol)
+		c.emit(OpSetVar, depth, symbol.Index)
+	case *ast.ReturnStatement:
+		if err := c.compileExpression(statement.ReturnValue, c.inFunction()); err != nil {
+			return err
+		}
+		c.emit(OpReturnValue)
+	case *ast.ExpressionStatement:
+		if err := c.compileExpression(statement.Expression, false); err != nil {
+			return err
+		}
+		c.emit(OpPop)
+	default:
+		return c.errorf(statement, "`%s` is not supported by the compiler yet", statement.TokenLiteral())
+	}
+
+	return nil
+}
+
+// compileRedefinition compiles `value name = value` or `constant name = value`
+// where name is already a constant in this scope, which fails once the value
+// has been evaluated and checked against name's type annotation, like it does
+// in the evaluator.
+func (c *Compiler) compileRedefinition(name *ast.Identifier, value ast.Expression) error {
+	if err := c.compileBinding(value, name.Value); err != nil {
+		return err
+	}
+	c.emitCheckType(&Symbol{Name: name.Value, Type: name.TypeName()})
+	c.emitFail("Cannot redefine constant `%s`.", name.Value)
+	return nil
+}
+
+// compileBinding compiles the value of a binding, naming it if it is a function literal.
+func (c *Compiler) compileBinding(value ast.Expression, name string) error {
+	if fnLit, ok := value.(*ast.FunctionLiteral); ok {
+		return c.compileFunctionLiteral(fnLit, name)
+	}
+
+	return c.compileExpression(value, false)
+}
+
+//
+// COMPILING/EXPRESSIONS
+//
+
+// compileExpression compiles an expression that leaves its value on the stack.
+// Calls in tail position are emitted as OpTailCall.
+func (c *Compiler) compileExpression(expression ast.Expression, isTail bool) error {
+	defer c.at(expression)()
+
+	switch node := expression.(type) {
+	case *ast.IntegerLiteral:
+		if node.Big != nil {
+			c.emit(OpConstant, c.addConstant(&objects.BigInteger{Value: node.Big}))
+			break
+		}
+		c.emit(OpConstant, c.addConstant(&objects.Integer{Value: node.Value}))
+	case *ast.FloatLiteral:
+		c.emit(OpConstant, c.addConstant(&objects.Float{Value: node.Value}))
+	case *ast.StringLiteral:
+		c.emit(OpConstant, c.addConstant(&objects.String{Value: node.Value}))
+	case *ast.BooleanLiteral:
+		if node.Value {
+			c.emit(OpTrue)
+		} else {
+			c.emit(OpFalse)
+		}
+	case *ast.PrefixExpression:
+		if err := c.compileExpression(node.Right, false); err != nil {
+			return err
+		}
+		c.emit(OpPrefix, c.addName(node.Operator))
+	case *ast.InfixExpression:
+		// The evaluator evaluates the right operand first; so do we.
+		if err := c.compileExpression(node.Right, false); err != nil {
+			return err
+		}
+		if err := c.compileExpression(node.Left, false); err != nil {
+			return err
+		}
+		c.emit(OpInfix, c.addName(node.Operator))
+	case *ast.IfExpression:
+		return c.compileIfExpression(node, isTail)
+	case *ast.MatchExpression:
+		return c.compileMatchExpression(node, isTail)
+	case *ast.Identifier:
+		if symbol, depth, ok := c.symbols().Resolve(node.Value); ok {
+			c.emit(OpGetVar, depth, symbol.Index)
+		} else if builtin, ok := evaluator.LookupBuiltin(node.Value); ok {
+			index, ok := c.builtins[builtin]
+			if !ok {
+				index = c.addConstant(builtin)
+				c.builtins[builtin] = index
+			}
+			c.emit(OpConstant, index)
+		} else {
+			c.emit(OpUndefined, c.addName(node.Value))
+		}
+	case *ast.FunctionLiteral:
+		return c.compileFunctionLiteral(node, "")
+	case *ast.ImportExpression:
+		return c.compileImport(node)
+	case *ast.AssignmentExpression:
+		if err := c.compileExpression(node.Value, false); err != nil {
+			return err
+		}
+		return c.compileAssignment(node, node.Name, strings.TrimSuffix(node.Operator, "="))
+	case *ast.IncrementExpression:
+		c.emit(OpConstant, c.addConstant(&objects.Integer{Value: 1}))
+		return c.compileAssignment(node, node.Name, node.Operator[:1])
+	case *ast.CallExpression:
+		return c.compileCallExpression(node, isTail)
+	case *ast.ArrayLiteral:
+		for _, el := range node.Elements {
+			if err := c.compileExpression(el, false); err != nil {
+				return err
+			}
+		}
+		c.emit(OpArray, len(node.Elements))
+	case *ast.TemplateLiteral:
+		for _, part := range node.Parts {
+			if err := c.compileExpression(part, false); err != nil {
+				return err
+			}
+		}
+		c.emit(OpTemplate, len(node.Parts))
+	case *ast.MapLiteral:
+		for key, value := range node.Pairs {
+			if err := c.compileExpression(key, false); err != nil {
+				return err
+			}
+			if err := c.compileExpression(value, false); err != nil {
+				return err
+			}
+		}
+		c.emit(OpMap, len(node.Pairs))
+	case *ast.IndexExpression:
+		if err := c.compileExpression(node.Left, false); err != nil {
+			return err
+		}
+		if err := c.compileExpression(node.Index, false); err != nil {
+			return err
+		}
+		c.emit(OpIndex)
+	case *ast.MemberExpression:
+		if err := c.compileExpression(node.Object, false); err != nil {
+			return err
+		}
+		c.emit(OpMember, c.addName(node.Property.Value))
+	default:
+		return c.errorf(expression, "`%s` is not supported by the compiler yet", expression.TokenLiteral())
+	}
+
+	return nil
+}
+
+// emitFail raises a runtime error with the message. It stands in for code that
+// would fail in the evaluator, which only fails if it is run.
+func (c *Compiler) emitFail(format string, a ...interface{}) {
+	c.emit(OpFail, c.addName(fmt.Sprintf(format, a...)))
+}
+
+// emitCheckType checks that the value about to be stored in symbol matches
+// its type annotation, if it has one.
+func (c *Compiler) emitCheckType(symbol *Symbol) {
+	if symbol.Type == "" {
+		return
+	}
+
+	what := "`" + symbol.Name + "`"
+	c.emit(OpCheckType, c.addName(symbol.Type), c.addName(what))
+}
+
+// compileAssignment updates name to `name operator value`, where value is
+// already on the stack, and leaves the new value on the stack.
+func (c *Compiler) compileAssignment(node ast.Node, name *ast.Identifier, operator string) error {
+	symbol, depth, ok := c.symbols().Resolve(name.Value)
+	if !ok {
+		c.emit(OpUndefined, c.addName(name.Value))
+		return nil
+	}
+
+	c.emit(OpGetVar, depth, symbol.Index)
+	c.emit(OpInfix, c.addName(operator))
+	if symbol.Constant {
+		c.emitFail("Cannot update constant `%s`.", symbol.Name)
+		return nil
+	}
+	c.emitCheckType(symbol)
+	c.emit(OpSetVar, depth, symbol.Index)
+	c.emit(OpGetVar, depth, symbol.Index)
+	return nil
+}
+
+func (c *Compiler) compileIfExpression(node *ast.IfExpression, isTail bool) error {
+	if err := c.compileExpression(node.Condition, false); err != nil {
+		return err
+	}
+
+	jumpNotTruthy := c.emit(OpJumpNotTruthy, 0)
+
+	if err := c.compileBlock(node.Consequence.Statements, isTail); err != nil {
+		return err
+	}
+
+	jump := c.emit(OpJump, 0)
+	c.changeOperands(jumpNotTruthy, len(c.currentScope().function.Instructions))
+
+	if node.Alternative == nil {
+		c.emit(OpNull)
+	} else if err := c.compileBlock(node.Alternative.Statements, isTail); err != nil {
+		return err
+	}
+
+	c.changeOperands(jump, len(c.currentScope().function.Instructions))
+	return nil
+}
+
+// compileMatchExpression keeps the subject on the stack while each arm's
+// OpMatch tests it, pushing the values its pattern binds if it matches.
+func (c *Compiler) compileMatchExpression(node *ast.MatchExpression, isTail bool) error {
+	if err := c.compileExpression(node.Subject, false); err != nil {
+		return err
+	}
+
+	ends := []int{}
+	for _, arm := range node.Arms {
+		restore := c.at(arm.Pattern)
+		pattern := c.addConstant(&Pattern{Pattern: arm.Pattern})
+		next := []int{c.emit(OpMatch, pattern, 0)}
+
+		names := ast.PatternBindings(arm.Pattern)
+		symbols := make([]*Symbol, len(names))
+		constant := -1 // the first name that is a constant, which the arm fails to bind
+		for i, name := range names {
+			symbols[i] = c.symbols().Define(name.Value)
+			if symbols[i].Constant && constant < 0 {
+				constant = i
+			}
+		}
+
+		if arm.Guard != nil {
+			// The guard sees the values in slots of their own, which are only
+			// copied to the names it binds if it holds.
+			temporaries := make([]*Symbol, len(names))
+			unshadows := make([]func(), len(names))
+			for i, name := range names {
+				temporaries[i], unshadows[i] = c.symbols().Shadow(name.Value)
+			}
+			for i := len(names) - 1; i >= 0; i-- {
+				c.emit(OpSetVar, 0, temporaries[i].Index)
+			}
+			restore()
+
+			err := c.compileExpression(arm.Guard, false)
+			for i := len(names) - 1; i >= 0; i-- {
+				unshadows[i]()
+			}
+			if err != nil {
+				return err
+			}
+			next = append(next, c.emit(OpJumpNotTruthy, 0))
+
+			for i := range names {
+				c.emit(OpGetVar, 0, temporaries[i].Index)
+			}
+			restore = c.at(arm.Pattern)
+		}
+
+		if constant >= 0 {
+			restore()
+			restore = c.at(names[constant])
+			c.emitFail("Cannot redefine constant `%s`.", names[constant].Value)
+		}
+		for i := len(names) - 1; i >= 0; i-- {
+			symbols[i].Type = ""
+			c.emit(OpSetVar, 0, symbols[i].Index)
+		}
+		restore()
+
+		c.emit(OpPop)
+		if err := c.compileBlock(arm.Body.Statements, isTail); err != nil {
+			return err
+		}
+		ends = append(ends, c.emit(OpJump, 0))
+
+		target := len(c.currentScope().function.Instructions)
+		c.changeOperands(next[0], pattern, target)
+		if len(next) > 1 {
+			c.changeOperands(next[1], target)
+		}
+	}
+
+	c.emit(OpNoMatch)
+	for _, end := range ends {
+		c.changeOperands(end, len(c.currentScope().function.Instructions))
+	}
+	return nil
+}
+
+func (c *Compiler) compileFunctionLiteral(node *ast.FunctionLiteral, name string) error {
+	defer c.at(node)()
+
+	if name == "" {
+		name = "<anonymous>"
+	}
+
+	c.enterScope(NewEnclosedSymbolTable(c.symbols()), name)
+	parameterTypes := make([]string, len(node.Parameters))
+	numRequired := len(node.Parameters)
+	for i, param := range node.Parameters {
+		symbol := c.symbols().Define(param.Value)
+		if !node.Variadic || i < len(node.Parameters)-1 {
+			symbol.Type = param.TypeName()
+		}
+		parameterTypes[i] = param.TypeName()
+		if i < len(node.Defaults) && node.Defaults[i] != nil && numRequired > i {
+			numRequired = i
+		}
+	}
+	if node.Variadic && numRequired == len(node.Parameters) {
+		numRequired--
+	}
+	for _, value := range node.Defaults {
+		c.hoistExpression(value)
+	}
+	c.hoist(node.Body.Statements)
+
+	// Parameters left unset by the call get their default values first.
+	for i, value := range node.Defaults {
+		if value == nil {
+			continue
+		}
+		param := node.Parameters[i]
+		restore := c.at(value)
+		jumpIfSet := c.emit(OpJumpIfSet, 0, i)
+		if err := c.compileExpression(value, false); err != nil {
+			return err
+		}
+		if param.TypeName() != "" {
+			what := fmt.Sprintf("parameter `%s` of `%s`", param.Value, name)
+			c.emit(OpCheckType, c.addName(param.TypeName()), c.addName(what))
+		}
+		c.emit(OpSetVar, 0, i)
+		c.changeOperands(jumpIfSet, len(c.currentScope().function.Instructions), i)
+		restore()
+	}
+
+	if err := c.compileBlock(node.Body.Statements, true); err != nil {
+		return err
+	}
+	c.emit(OpReturnValue)
+
+	function := c.leaveScope()
+	function.NumParameters = len(node.Parameters)
+	function.NumRequired = numRequired
+	function.Variadic = node.Variadic
+	function.ParameterTypes = parameterTypes
+
+	c.emit(OpClosure, c.addConstant(function))
+	return nil
+}
+
+// compileImport compiles the file node imports, once, into a function the
+// vm runs the first time OpImport needs it. Its bindings become the module's.
+func (c *Compiler) compileImport(node *ast.ImportExpression) error {
+	path, err := evaluator.ResolveImportPath(node)
+	if err != nil {
+		return c.errorf(node, "could not resolve import %q: %s", node.Path, err)
+	}
+
+	if index, ok := c.modules[path]; ok {
+		c.emit(OpImport, index)
+		return nil
+	}
+
+	for idx, importing := range c.importStack {
+		if importing == path {
+			cycle := append(append([]string{}, c.importStack[idx:]...), path)
+			return c.errorf(node, "import cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+
+	program, parseErr := evaluator.ParseModule(node, path)
+	if parseErr != nil {
+		return c.errorf(node, "%s", parseErr.Message)
+	}
+
+	c.importStack = append(c.importStack, path)
+	defer func() { c.importStack = c.importStack[:len(c.importStack)-1] }()
+
+	// Modules see only their own bindings and the builtins, like a program.
+	position := c.position
+	c.enterScope(NewSymbolTable(), path)
+	c.hoist(program.Statements)
+	if err := c.compileBlock(program.Statements, false); err != nil {
+		return err
+	}
+	c.emit(OpReturnValue)
+	module := c.leaveScope()
+	c.position = position
+
+	index := c.addConstant(module)
+	c.modules[path] = index
+	c.emit(OpImport, index)
+	return nil
+}
+
+func (c *Compiler) compileCallExpression(node *ast.CallExpression, isTail bool) error {
+	if err := c.compileExpression(node.Function, false); err != nil {
+		return err
+	}
+
+	for _, arg := range node.Arguments {
+		if err := c.compileExpression(arg, false); err != nil {
+			return err
+		}
+	}
+
+	// Calls are reported at the callee, like stack frames in the evaluator.
+	defer c.at(node.Function)()
+	if isTail {
+		c.emit(OpTailCall, len(node.Arguments))
+	} else {
+		c.emit(OpCall, len(node.Arguments))
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package compiler
+
+// Symbol is a name bound in a scope, resolved to a slot in that scope's environment.
+type Symbol struct {
+	Name     string
+	Index    int
+	Constant bool
+	Type     string // the type annotation of the binding in effect, if any
+}
+
+// SymbolTable tracks the bindings of one scope: the program itself or a function body.
+// Blocks inside a scope (the branches of an `if`) share its table, like they share
+// a Machine in the evaluator.
+type SymbolTable struct {
+	outer  *SymbolTable
+	store  map[string]*Symbol
+	hidden int // slots reserved by Shadow that store no longer has a symbol for
+}
+
+func NewSymbolTable() *SymbolTable {
+	return &SymbolTable{store: make(map[string]*Symbol)}
+}
+
+func NewEnclosedSymbolTable(outer *SymbolTable) *SymbolTable {
+	table := NewSymbolTable()
+	table.outer = outer
+	return table
+}
+
+// Define binds name in this scope, reusing its slot if it is already bound here.
+func (s *SymbolTable) Define(name string) *Symbol {
+	if symbol, ok := s.store[name]; ok {
+		return symbol
+	}
+
+	symbol := &Symbol{Name: name, Index: s.NumDefinitions()}
+	s.store[name] = symbol
+	return symbol
+}
+
+// Shadow binds name to a new slot in this scope until the returned func is
+// called, when name goes back to its previous binding, if any. The slot
+// stays reserved, so it is never given to another name.
+func (s *SymbolTable) Shadow(name string) (*Symbol, func()) {
+	previous, ok := s.store[name]
+	symbol := &Symbol{Name: name, Index: s.NumDefinitions()}
+	s.store[name] = symbol
+	if ok {
+		s.hidden++ // the new slot replaces previous's in store
+	}
+
+	return symbol, func() {
+		if ok {
+			s.store[name] = previous
+		} else {
+			delete(s.store, name)
+			s.hidden++
+		}
+	}
+}
+
+// Resolve finds name in this scope or an enclosing one, returning how many
+// scopes out it was found.
+func (s *SymbolTable) Resolve(name string) (*Symbol, int, bool) {
+	depth := 0
+	for table := s; table != nil; table = table.outer {
+		if symbol, ok := table.store[name]; ok {
+			return symbol, depth, true
+		}
+		depth++
+	}
+
+	return nil, 0, false
+}
+
+// NumDefinitions is the number of slots an environment for this scope needs.
+func (s *SymbolTable) NumDefinitions() int {
+	return len(s.store) + s.hidden
+}
@@ -0,0 +1,88 @@
+package evaluator
+
+import (
+	"math"
+	"math/big"
+	"sepia/objects"
+)
+
+// maxPowerBits keeps `**` from trying to build numbers too large to hold in
+// memory: it is the most bits a result may need.
+const maxPowerBits = 1 << 25
+
+func isSmallInteger(obj objects.Object) bool {
+	_, ok := obj.(*objects.Integer)
+	return ok
+}
+
+// toBig widens an INTEGER object, small or big, to a *big.Int.
+func toBig(obj objects.Object) *big.Int {
+	switch obj := obj.(type) {
+	case *objects.Integer:
+		return big.NewInt(obj.Value)
+	case *objects.BigInteger:
+		return obj.Value
+	default:
+		return new(big.Int)
+	}
+}
+
+func evalBigInfixExpression(operator string, leftVal, rightVal *big.Int) objects.Object {
+	switch operator {
+	case "+":
+		return objects.NewBigInteger(new(big.Int).Add(leftVal, rightVal))
+	case "-":
+		return objects.NewBigInteger(new(big.Int).Sub(leftVal, rightVal))
+	case "*":
+		return objects.NewBigInteger(new(big.Int).Mul(leftVal, rightVal))
+	case "/", "//", "%":
+		if rightVal.Sign() == 0 {
+			return newError("division by zero: %s %s 0", leftVal, operator)
+		}
+
+		quotient, remainder := new(big.Int).QuoRem(leftVal, rightVal, new(big.Int))
+		if operator == "/" {
+			return objects.NewBigInteger(quotient)
+		}
+
+		// Floor the quotient, so `%` takes the sign of the divisor as it does for small integers.
+		if remainder.Sign() != 0 && remainder.Sign() != rightVal.Sign() {
+			quotient.Sub(quotient, big.NewInt(1))
+			remainder.Add(remainder, rightVal)
+		}
+		if operator == "//" {
+			return objects.NewBigInteger(quotient)
+		}
+		return objects.NewBigInteger(remainder)
+	case "**":
+		if rightVal.Sign() < 0 {
+			return &objects.Float{Value: math.Pow(toFloat(&objects.BigInteger{Value: leftVal}), toFloat(&objects.BigInteger{Value: rightVal}))}
+		}
+		// 0, 1 and -1 stay small whatever the exponent; anything else needs
+		// up to as many bits as it has for each time it is multiplied.
+		if bits := leftVal.BitLen(); bits > 1 {
+			limit := big.NewInt(maxPowerBits / int64(bits))
+			if rightVal.Cmp(limit) > 0 {
+				if bits > 64 {
+					return newError("exponent too large: a %d-bit integer ** %s", bits, rightVal)
+				}
+				return newError("exponent too large: %s ** %s", leftVal, rightVal)
+			}
+		}
+		return objects.NewBigInteger(new(big.Int).Exp(leftVal, rightVal, nil))
+	case "<":
+		return toBool(leftVal.Cmp(rightVal) < 0)
+	case ">":
+		return toBool(leftVal.Cmp(rightVal) > 0)
+	case "<=":
+		return toBool(leftVal.Cmp(rightVal) <= 0)
+	case ">=":
+		return toBool(leftVal.Cmp(rightVal) >= 0)
+	case "==":
+		return toBool(leftVal.Cmp(rightVal) == 0)
+	case "!=":
+		return toBool(leftVal.Cmp(rightVal) != 0)
+	default:
+		return newTypeError("unknown operator: %s %s %s", objects.INTEGER_OBJ, operator, objects.INTEGER_OBJ)
+	}
+}
@@ -2,96 +2,217 @@ package evaluator
 
 import (
 	"fmt"
+	"math"
+	"math/big"
+	"path/filepath"
 	"sepia/ast"
 	"sepia/objects"
+	"sepia/token"
 	"strconv"
+	"strings"
 )
 
 func newError(format string, a ...interface{}) *objects.Error {
 	return &objects.Error{Message: fmt.Sprintf(format, a...)}
 }
 
+// newTypeError reports an operation the types of its operands don't support.
+func newTypeError(format string, a ...interface{}) *objects.Error {
+	err := newError(format, a...)
+	err.Kind = objects.TypeError
+	return err
+}
+
 var (
 	TRUE  = &objects.Boolean{Value: true}
 	FALSE = &objects.Boolean{Value: false}
 	NULL  = &objects.Null{}
 )
 
-func Eval(node ast.Node, machine *objects.Machine) objects.Object {
+// DefaultMaxCallDepth is the MaxCallDepth of a new Interpreter.
+const DefaultMaxCallDepth = 10000
+
+// Interpreter evaluates Sepia programs, keeping the state of the program being
+// run: the functions being applied, the files being imported and the modules
+// imported so far. An Interpreter runs one program at a time; a host running
+// programs concurrently gives each its own.
+type Interpreter struct {
+	// MaxCallDepth is the deepest the Sepia call stack may grow before a call
+	// fails with an error. Tail calls don't count towards it. Zero disables the limit.
+	MaxCallDepth int
+	// CheckedArithmetic makes integer arithmetic that overflows an int64 a runtime
+	// error instead of promoting the result to an arbitrary-precision integer.
+	CheckedArithmetic bool
+
+	// callStack holds the Sepia functions currently being applied, outermost first.
+	callStack []objects.Frame
+	// importStack holds the files currently being imported, used to detect cycles.
+	importStack []string
+	// modules caches every successfully imported file by absolute path, so each
+	// file is evaluated at most once by this Interpreter.
+	modules map[string]*objects.Module
+}
+
+func New() *Interpreter {
+	return &Interpreter{MaxCallDepth: DefaultMaxCallDepth, modules: map[string]*objects.Module{}}
+}
+
+// Run evaluates node like Eval, and is what hosts (the CLI, the REPL) should
+// call: a Go panic anywhere inside is reported as an internal error instead of
+// taking the host down.
+func (in *Interpreter) Run(node ast.Node, machine *objects.Machine) (result objects.Object) {
+	defer func() {
+		if r := recover(); r != nil {
+			result = newError("internal error: %v", r)
+
+			in.callStack = nil
+			in.importStack = nil
+		}
+	}()
+
+	return in.Eval(node, machine)
+}
+
+// RunFile runs program, read from the file at path, like Run. The file counts
+// as being imported while it runs, so an import cycle leading back to it is
+// reported instead of evaluating it a second time.
+func (in *Interpreter) RunFile(program *ast.Program, machine *objects.Machine, path string) objects.Object {
+	if abs, err := filepath.Abs(path); err == nil {
+		in.importStack = append(in.importStack, abs)
+		defer func() {
+			if len(in.importStack) > 0 { // Run clears it after a panic
+				in.importStack = in.importStack[:len(in.importStack)-1]
+			}
+		}()
+	}
+
+	return in.Run(program, machine)
+}
+
+// Eval evaluates node in machine. Errors raised while evaluating are stamped
+// with the position of the innermost node they came from.
+func (in *Interpreter) Eval(node ast.Node, machine *objects.Machine) objects.Object {
+	return withPosition(in.eval(node, machine), node)
+}
+
+// withPosition stamps an error that doesn't know where it came from with node's position.
+func withPosition(result objects.Object, node ast.Node) objects.Object {
+	if err, ok := result.(*objects.Error); ok && !err.Position.IsValid() && node != nil {
+		err.Position = node.Pos()
+	}
+
+	return result
+}
+
+func (in *Interpreter) eval(node ast.Node, machine *objects.Machine) objects.Object {
 	switch node := node.(type) {
 	// Statements
 	case *ast.Program:
-		return evalProgram(node, machine)
+		return in.evalProgram(node, machine)
 	case *ast.ExpressionStatement:
-		return Eval(node.Expression, machine)
+		return in.Eval(node.Expression, machine)
 	case *ast.ReturnStatement:
-		val := Eval(node.ReturnValue, machine)
+		val := in.Eval(node.ReturnValue, machine)
 		if isError(val) {
 			return val
 		}
 		return &objects.ReturnValue{Value: val}
 	case *ast.BlockStatement:
-		return evalBlockStatement(node, machine)
+		return in.evalBlockStatement(node, machine)
 	case *ast.ValueStatement:
-		val := Eval(node.Value, machine)
+		val := in.Eval(node.Value, machine)
+		if isError(val) {
+			return val
+		}
+		if err := objects.CheckType("`"+node.Name.Value+"`", node.Name.TypeName(), val); err != nil {
+			return err
+		}
+		nameFunction(val, node.Name.Value)
+		if result := machine.Set(node.Name.Value, val); isError(result) {
+			return result
+		}
+		machine.SetType(node.Name.Value, node.Name.TypeName())
+	case *ast.ConstantStatement:
+		val := in.Eval(node.Value, machine)
 		if isError(val) {
 			return val
 		}
-		machine.Set(node.Name.Value, val)
+		if err := objects.CheckType("`"+node.Name.Value+"`", node.Name.TypeName(), val); err != nil {
+			return err
+		}
+		nameFunction(val, node.Name.Value)
+		if result := machine.SetConstant(node.Name.Value, val); isError(result) {
+			return result
+		}
+	case *ast.StructureStatement:
+		structure := newStructure(node)
+		if isError(structure) {
+			return structure
+		}
+		if result := machine.SetConstant(node.Name.Value, structure); isError(result) {
+			return result
+		}
 	case *ast.UpdateStatement:
-		val := Eval(node.Value, machine)
+		val := in.Eval(node.Value, machine)
 		if isError(val) {
 			return val
 		}
-		machine.Update(node.Name.Value, val)
+		if result := machine.Update(node.Name.Value, val); isError(result) {
+			return result
+		}
 
 	// Expressions
 	case *ast.IntegerLiteral:
+		if node.Big != nil {
+			return &objects.BigInteger{Value: node.Big}
+		}
 		return &objects.Integer{Value: node.Value}
+	case *ast.FloatLiteral:
+		return &objects.Float{Value: node.Value}
 	case *ast.BooleanLiteral:
 		return toBool(node.Value)
 	case *ast.CallExpression:
-		function := Eval(node.Function, machine)
-		if isError(function) {
-			return function
-		}
-		args := evalExpressions(node.Arguments, machine)
-		if len(args) == 1 && isError(args[0]) {
-			return args[0]
-		}
-
-		return applyFunction(function, args)
+		return in.evalCallExpression(node, machine, false)
 	case *ast.StringLiteral:
 		return &objects.String{Value: node.Value}
 
+	case *ast.TemplateLiteral:
+		parts := in.evalExpressions(node.Parts, machine)
+		if len(parts) == 1 && isError(parts[0]) {
+			return parts[0]
+		}
+		return evalTemplate(parts)
+
 	case *ast.PrefixExpression:
-		right := Eval(node.Right, machine)
+		right := in.Eval(node.Right, machine)
 		if isError(right) {
 			return right
 		}
-		return evalPrefixExpression(node.Operator, right)
+		return in.evalPrefixExpression(node.Operator, right)
 	case *ast.InfixExpression:
-		right := Eval(node.Right, machine)
+		right := in.Eval(node.Right, machine)
 		if isError(right) {
 			return right
 		}
-		left := Eval(node.Left, machine)
+		left := in.Eval(node.Left, machine)
 		if isError(left) {
 			return left
 		}
-		return evalInfixExpression(node.Operator, left, right)
+		return in.evalInfixExpression(node.Operator, left, right)
 	case *ast.IfExpression:
-		return evalIfExpression(node, machine)
+		return in.evalIfExpression(node, machine)
+	case *ast.MatchExpression:
+		return in.evalMatchExpression(node, machine, false)
 	case *ast.Identifier:
 		return evalIdentifier(node, machine)
 	case *ast.FunctionLiteral:
 		params := node.Parameters
 		body := node.Body
-		return &objects.Function{Parameters: params, Machine: machine, Body: body}
+		return &objects.Function{Parameters: params, Defaults: node.Defaults, Variadic: node.Variadic, Machine: machine, Body: body}
 
 	case *ast.ArrayLiteral:
 
-		elements := evalExpressions(node.Elements, machine)
+		elements := in.evalExpressions(node.Elements, machine)
 		if len(elements) == 1 && isError(elements[0]) {
 			return elements[0]
 		}
@@ -99,28 +220,59 @@ func Eval(node ast.Node, machine *objects.Machine) objects.Object {
 
 	case *ast.IndexExpression:
 
-		left := Eval(node.Left, machine)
+		left := in.Eval(node.Left, machine)
 		if isError(left) {
 			return left
 		}
-		index := Eval(node.Index, machine)
+		index := in.Eval(node.Index, machine)
 		if isError(index) {
 			return index
 		}
 		return evalIndexExpression(left, index)
 	case *ast.MapLiteral:
-		return evalMapLiteral(node, machine)
+		return in.evalMapLiteral(node, machine)
+	case *ast.AssignmentExpression:
+		val := in.Eval(node.Value, machine)
+		if isError(val) {
+			return val
+		}
+		return in.evalAssignment(node.Name.Value, strings.TrimSuffix(node.Operator, "="), val, machine)
+	case *ast.IncrementExpression:
+		return in.evalAssignment(node.Name.Value, node.Operator[:1], &objects.Integer{Value: 1}, machine)
+	case *ast.MemberExpression:
+		return in.evalMemberExpression(node, machine)
+	case *ast.ImportExpression:
+		return in.evalImportExpression(node)
 	default:
 		return newError("I literally have no clue wtf that is. RTFM pls.")
 	}
 	return nil
 }
 
-func evalMapLiteral(node *ast.MapLiteral, machine *objects.Machine) objects.Object {
+// evalAssignment updates the binding name to `name operator val`, returning the new value.
+func (in *Interpreter) evalAssignment(name string, operator string, val objects.Object, machine *objects.Machine) objects.Object {
+	current, ok := machine.Get(name)
+	if !ok {
+		return newError("identifier not found: " + name)
+	}
+
+	updated := in.evalInfixExpression(operator, current, val)
+	if isError(updated) {
+		return updated
+	}
+
+	if result := machine.Update(name, updated); isError(result) {
+		return result
+	}
+
+	return updated
+}
+
+func (in *Interpreter) evalMapLiteral(node *ast.MapLiteral, machine *objects.Machine) objects.Object {
 	pairs := make(map[objects.MapKey]objects.MapPair)
 
 	for keyN, valueN := range node.Pairs {
-		key := Eval(keyN, machine)
+		key := in.Eval(keyN, machine)
 
 		if isError(key) {
 			return key
@@ -128,10 +280,10 @@ func evalMapLiteral(node *ast.MapLiteral, machine *objects.Machine) objects.Obje
 
 		mapKey, ok := key.(objects.Mappable)
 		if !ok {
-			return newError("unusable as map key: %s", key.Type())
+			return newTypeError("unusable as map key: %s", key.Type())
 		}
 
-		value := Eval(valueN, machine)
+		value := in.Eval(valueN, machine)
 		if isError(value) {
 			return value
 		}
@@ -142,26 +294,118 @@ func evalMapLiteral(node *ast.MapLiteral, machine *objects.Machine) objects.Obje
 	return &objects.Map{Pairs: pairs}
 }
 
-func applyFunction(fn objects.Object, args []objects.Object) objects.Object {
+// nameFunction gives an anonymous function the name it is first bound to, for stack traces.
+func nameFunction(val objects.Object, name string) {
+	if fn, ok := val.(*objects.Function); ok && fn.Name == "" {
+		fn.Name = name
+	}
+}
+
+func functionName(fn *objects.Function) string {
+	if fn.Name == "" {
+		return "<anonymous>"
+	}
+	return fn.Name
+}
+
+func (in *Interpreter) applyFunction(fn objects.Object, args []objects.Object, callSite token.Position) objects.Object {
 	switch fn := fn.(type) {
 	case *objects.Function:
-		extendedLocMachine := extendLocalMachine(fn, args)
-		evaluated := Eval(fn.Body, extendedLocMachine)
-		return unwrapReturnValue(evaluated)
+		if in.MaxCallDepth > 0 && len(in.callStack) >= in.MaxCallDepth {
+			err := newError("maximum call depth %d exceeded in `%s`", in.MaxCallDepth, functionName(fn))
+			err.Position = callSite
+			err.Trace = make([]objects.Frame, len(in.callStack))
+			copy(err.Trace, in.callStack)
+			return err
+		}
+
+		in.callStack = append(in.callStack, objects.Frame{Function: functionName(fn), CallSite: callSite})
+		defer func() { in.callStack = in.callStack[:len(in.callStack)-1] }()
+
+		// Calls in tail position come back as a tailCall instead of recursing,
+		// and are run by this loop so they don't grow the Go stack.
+		for {
+			var evaluated objects.Object
+			if extendedLocMachine, result := in.extendLocalMachine(fn, args); result != nil {
+				// Arguments rejected before fn started are reported at the call,
+				// without fn in the trace. Errors from a default value are fn's own.
+				if err, ok := result.(*objects.Error); ok && !err.Position.IsValid() {
+					err.Position = in.callStack[len(in.callStack)-1].CallSite
+					err.Trace = make([]objects.Frame, len(in.callStack)-1)
+					copy(err.Trace, in.callStack)
+				}
+				evaluated = result
+			} else {
+				evaluated = unwrapReturnValue(in.evalBlockTail(fn.Body, extendedLocMachine, true))
+			}
+
+			if call, ok := evaluated.(*tailCall); ok {
+				fn, args = call.function, call.args
+				in.callStack[len(in.callStack)-1] = objects.Frame{Function: functionName(fn), CallSite: call.callSite}
+				continue
+			}
+
+			if err, ok := evaluated.(*objects.Error); ok && err.Trace == nil {
+				err.Trace = make([]objects.Frame, len(in.callStack))
+				copy(err.Trace, in.callStack)
+			}
+
+			return evaluated
+		}
 	case *objects.Builtin:
 		return fn.Fn(args...)
+	case *objects.Structure:
+		return instantiate(fn, args)
 	default:
 		return newError("not a function: %s", fn.Type())
 	}
 }
 
-func extendLocalMachine(fn *objects.Function, args []objects.Object,
-) *objects.Machine {
+// extendLocalMachine binds fn's parameters to args in a new scope, checking
+// the number of arguments and any type annotations. Missing arguments take
+// their default values, which can refer to the parameters before them, and a
+// `...rest` parameter gets an array of the remaining arguments.
+func (in *Interpreter) extendLocalMachine(fn *objects.Function, args []objects.Object,
+) (*objects.Machine, objects.Object) {
+	if min, max := fn.Arity(); len(args) < min || max >= 0 && len(args) > max {
+		return nil, objects.ArityError(functionName(fn), min, max, len(args))
+	}
+
 	machine := objects.NewLocalMachine(fn.Machine)
 	for paramIdx, param := range fn.Parameters {
-		machine.Set(param.Value, args[paramIdx])
+		what := fmt.Sprintf("parameter `%s` of `%s`", param.Value, functionName(fn))
+
+		if fn.Variadic && paramIdx == len(fn.Parameters)-1 {
+			rest := []objects.Object{}
+			if paramIdx < len(args) {
+				rest = append(rest, args[paramIdx:]...)
+			}
+			for _, arg := range rest {
+				if err := objects.CheckType("each of "+what, param.TypeName(), arg); err != nil {
+					return nil, err
+				}
+			}
+			machine.Set(param.Value, &objects.Array{Elements: rest})
+			break
+		}
+
+		var arg objects.Object
+		if paramIdx < len(args) {
+			arg = args[paramIdx]
+		} else if arg = in.Eval(fn.Defaults[paramIdx], machine); isError(arg) {
+			return nil, arg
+		}
+
+		if err := objects.CheckType(what, param.TypeName(), arg); err != nil {
+			if paramIdx >= len(args) {
+				err.Position = fn.Defaults[paramIdx].Pos()
+			}
+			return nil, err
+		}
+		machine.Set(param.Value, arg)
+		machine.SetType(param.Value, param.TypeName())
 	}
-	return machine
+	return machine, nil
 }
 func unwrapReturnValue(obj objects.Object) objects.Object {
 	if returnValue, ok := obj.(*objects.ReturnValue); ok {
@@ -170,14 +414,14 @@ func unwrapReturnValue(obj objects.Object) objects.Object {
 	return obj
 }
 
-func evalExpressions(
+func (in *Interpreter) evalExpressions(
 	expressions []ast.Expression,
 	machine *objects.Machine,
 ) []objects.Object {
 	var result []objects.Object
 
 	for _, e := range expressions {
-		evaluated := Eval(e, machine)
+		evaluated := in.Eval(e, machine)
 		if isError(evaluated) {
 			return []objects.Object{evaluated}
 		}
@@ -187,15 +431,15 @@ func evalExpressions(
 	return result
 }
 
-func evalBlockStatement(block *ast.BlockStatement, machine *objects.Machine) objects.Object {
+func (in *Interpreter) evalBlockStatement(block *ast.BlockStatement, machine *objects.Machine) objects.Object {
 	var result objects.Object
 
 	for _, statement := range block.Statements {
-		result = Eval(statement, machine)
+		result = in.Eval(statement, machine)
 
 		if result != nil {
 			rt := result.Type()
-			if rt == objects.RETURN_VALUE_OBJ || rt == objects.ERROR_OBJ {
+			if rt == objects.RETURN_VALUE_OBJ || rt == objects.ERROR_OBJ || rt == objects.EXIT_OBJ {
 				return result
 			}
 		}
@@ -204,24 +448,28 @@ func evalBlockStatement(block *ast.BlockStatement, machine *objects.Machine) obj
 	return result
 }
 
+// isError reports whether obj must abort evaluation: either a runtime error or
+// a request to exit, which unwinds the program the same way.
 func isError(obj objects.Object) bool {
 	if obj != nil {
-		return obj.Type() == objects.ERROR_OBJ
+		return obj.Type() == objects.ERROR_OBJ || obj.Type() == objects.EXIT_OBJ
 	}
 
 	return false
 }
 
-func evalProgram(program *ast.Program, machine *objects.Machine) objects.Object {
+func (in *Interpreter) evalProgram(program *ast.Program, machine *objects.Machine) objects.Object {
 	var result objects.Object
 
 	for _, statement := range program.Statements {
-		result = Eval(statement, machine)
+		result = in.Eval(statement, machine)
 		switch result := result.(type) {
 		case *objects.ReturnValue:
 			return result.Value
 		case *objects.Error:
 			return result
+		case *objects.Exit:
+			return result
 		}
 	}
 
@@ -242,10 +490,12 @@ func evalIndexExpression(left, index objects.Object) objects.Object {
 	switch {
 	case left.Type() == objects.ARRAY_OBJ && index.Type() == objects.INTEGER_OBJ:
 		return evalArrayIndexExpression(left, index)
+	case left.Type() == objects.STRING_OBJ && index.Type() == objects.INTEGER_OBJ:
+		return evalStringIndexExpression(left, index)
 	case left.Type() == objects.MAP_OBJ:
 		return evalMapIndexExp(left, index)
 	default:
-		return newError("index operator not supported: %s", left.Type())
+		return newTypeError("index operator not supported: %s", left.Type())
 	}
 }
 
@@ -254,7 +504,7 @@ func evalMapIndexExp(mapNode, index objects.Object) objects.Object {
 
 	key, ok := index.(objects.Mappable)
 	if !ok {
-		return newError("unusable as map key: %s", index.Type())
+		return newTypeError("unusable as map key: %s", index.Type())
 	}
 
 	pair, ok := obj.Pairs[key.MapKey()]
@@ -268,7 +518,11 @@ func evalMapIndexExp(mapNode, index objects.Object) objects.Object {
 
 func evalArrayIndexExpression(array, index objects.Object) objects.Object {
 	arr := array.(*objects.Array)
-	idx := index.(*objects.Integer).Value
+	integer, ok := index.(*objects.Integer)
+	if !ok {
+		return NULL // a BigInteger is always out of range
+	}
+	idx := integer.Value
 	max := int64(len(arr.Elements) - 1)
 
 	// TODO: Allow negative operators
@@ -279,10 +533,37 @@ func evalArrayIndexExpression(array, index objects.Object) objects.Object {
 	return arr.Elements[idx]
 }
 
+// evalTemplate joins the evaluated parts of an interpolated string, each
+// converted the way the `string` builtin would.
+func evalTemplate(parts []objects.Object) objects.Object {
+	var out strings.Builder
+
+	for _, part := range parts {
+		str := stringify(part)
+		if isError(str) {
+			return newError("cannot interpolate %s into a string", part.Type())
+		}
+		out.WriteString(str.(*objects.String).Value)
+	}
+
+	return &objects.String{Value: out.String()}
+}
+
+// evalStringIndexExpression returns the character at a rune index as a string.
+func evalStringIndexExpression(str, index objects.Object) objects.Object {
+	runes := []rune(str.(*objects.String).Value)
+	integer, ok := index.(*objects.Integer)
+	if !ok || integer.Value < 0 || integer.Value >= int64(len(runes)) {
+		return NULL
+	}
+
+	return &objects.String{Value: string(runes[integer.Value])}
+}
+
 // func evalStatements(stmts []ast.Statement, machine *objects.Machine) objects.Object {
 // 	var result objects.Object
 // 	for _, statement := range stmts {
-// 		result = Eval(statement, machine)
+// 		result = in.Eval(statement, machine)
 
 // 		if returnValue, ok := result.(*objects.ReturnValue); ok {
 // 			return returnValue.Value
@@ -299,14 +580,14 @@ func toBool(input bool) *objects.Boolean {
 	return FALSE
 }
 
-func evalPrefixExpression(operator string, right objects.Object) objects.Object {
+func (in *Interpreter) evalPrefixExpression(operator string, right objects.Object) objects.Object {
 	switch operator {
 	case "!":
 		return evalNegationOpExpression(right)
 	case "-":
-		return evalMinusOpExpression(right)
+		return in.evalMinusOpExpression(right)
 	default:
-		return newError("unknown operator: %s%s", operator, right.Type())
+		return newTypeError("unknown operator: %s%s", operator, right.Type())
 	}
 }
 
@@ -323,24 +604,36 @@ func evalNegationOpExpression(right objects.Object) objects.Object {
 	}
 }
 
-func evalMinusOpExpression(right objects.Object) objects.Object {
-
-	if right.Type() != objects.INTEGER_OBJ {
-		return newError("unknown operator: -%s", right.Type())
+func (in *Interpreter) evalMinusOpExpression(right objects.Object) objects.Object {
+	switch right := right.(type) {
+	case *objects.Integer:
+		if right.Value == math.MinInt64 {
+			if in.CheckedArithmetic {
+				return newError("integer overflow: -(%d)", right.Value)
+			}
+			return objects.NewBigInteger(new(big.Int).Neg(big.NewInt(right.Value)))
+		}
+		return &objects.Integer{Value: -right.Value}
+	case *objects.BigInteger:
+		return objects.NewBigInteger(new(big.Int).Neg(right.Value))
+	case *objects.Float:
+		return &objects.Float{Value: -right.Value}
+	default:
+		return newTypeError("unknown operator: -%s", right.Type())
 	}
-
-	value := right.(*objects.Integer).Value
-
-	return &objects.Integer{Value: -value}
 }
 
-func evalInfixExpression(
+func (in *Interpreter) evalInfixExpression(
 	operator string,
 	left, right objects.Object,
 ) objects.Object {
 	switch {
+	case isSmallInteger(left) && isSmallInteger(right):
+		return in.evalIntInfixExpression(operator, left, right)
 	case left.Type() == objects.INTEGER_OBJ && right.Type() == objects.INTEGER_OBJ:
-		return evalIntInfixExpression(operator, left, right)
+		return evalBigInfixExpression(operator, toBig(left), toBig(right))
+	case isNumeric(left) && isNumeric(right):
+		return evalFloatInfixExpression(operator, left, right)
 	case operator == "==":
 		return toBool(left == right)
 	case operator == "!=":
@@ -367,13 +660,13 @@ func evalInfixExpression(
 		return toBool(leftb && rightb)
 
 	case left.Type() != right.Type():
-		return newError("type mismatch: %s %s %s",
+		return newTypeError("type mismatch: %s %s %s",
 			left.Type(), operator, right.Type())
 	case left.Type() == objects.STRING_OBJ && right.Type() == objects.STRING_OBJ:
 		return evalStringInfixExpression(operator, left, right)
 
 	default:
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		return newTypeError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
 
 	}
 }
@@ -382,14 +675,14 @@ func evalStringInfixExpression(operator string,
 	left, right objects.Object,
 ) objects.Object {
 	if operator != "+" {
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		return newTypeError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 	leftVal := left.(*objects.String).Value
 	rightVal := right.(*objects.String).Value
 	return &objects.String{Value: leftVal + rightVal}
 }
 
-func evalIntInfixExpression(
+func (in *Interpreter) evalIntInfixExpression(
 	operator string,
 	left, right objects.Object,
 ) objects.Object {
@@ -398,13 +691,93 @@ func evalIntInfixExpression(
 
 	switch operator {
 	case "+":
-		return &objects.Integer{Value: leftVal + rightVal}
+		sum := leftVal + rightVal
+		if leftVal > 0 && rightVal > 0 && sum < 0 || leftVal < 0 && rightVal < 0 && sum >= 0 {
+			return in.evalOverflow(leftVal, operator, rightVal)
+		}
+		return &objects.Integer{Value: sum}
+	case "-":
+		difference := leftVal - rightVal
+		if leftVal >= 0 && rightVal < 0 && difference < 0 || leftVal < 0 && rightVal > 0 && difference >= 0 {
+			return in.evalOverflow(leftVal, operator, rightVal)
+		}
+		return &objects.Integer{Value: difference}
+	case "*":
+		product, ok := mulChecked(leftVal, rightVal)
+		if !ok {
+			return in.evalOverflow(leftVal, operator, rightVal)
+		}
+		return &objects.Integer{Value: product}
+	case "/", "//", "%":
+		if rightVal == 0 {
+			return newError("division by zero: %d %s 0", leftVal, operator)
+		}
+		if leftVal == math.MinInt64 && rightVal == -1 && operator != "%" {
+			return in.evalOverflow(leftVal, operator, rightVal)
+		}
+		switch operator {
+		case "/":
+			return &objects.Integer{Value: leftVal / rightVal}
+		case "//":
+			return &objects.Integer{Value: floorDiv(leftVal, rightVal)}
+		default:
+			return &objects.Integer{Value: leftVal - floorDiv(leftVal, rightVal)*rightVal}
+		}
+	case "**":
+		if rightVal < 0 {
+			return &objects.Float{Value: math.Pow(float64(leftVal), float64(rightVal))}
+		}
+		power, ok := intPow(leftVal, rightVal)
+		if !ok {
+			return in.evalOverflow(leftVal, operator, rightVal)
+		}
+		return &objects.Integer{Value: power}
+	case "<":
+		return toBool(leftVal < rightVal)
+	case ">":
+		return toBool(leftVal > rightVal)
+	case "<=":
+		return toBool(leftVal <= rightVal)
+	case ">=":
+		return toBool(leftVal >= rightVal)
+	case "==":
+		return toBool(leftVal == rightVal)
+	case "!=":
+		return toBool(leftVal != rightVal)
+
+	default:
+		return newTypeError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+func evalFloatInfixExpression(
+	operator string,
+	left, right objects.Object,
+) objects.Object {
+	leftVal := toFloat(left)
+	rightVal := toFloat(right)
+
+	switch operator {
+	case "+":
+		return &objects.Float{Value: leftVal + rightVal}
 	case "-":
-		return &objects.Integer{Value: leftVal - rightVal}
+		return &objects.Float{Value: leftVal - rightVal}
 	case "*":
-		return &objects.Integer{Value: leftVal * rightVal}
-	case "/":
-		return &objects.Integer{Value: leftVal / rightVal}
+		return &objects.Float{Value: leftVal * rightVal}
+	case "/", "//", "%":
+		if rightVal == 0 {
+			return newError("division by zero: %s %s 0", left.Inspect(), operator)
+		}
+		switch operator {
+		case "/":
+			return &objects.Float{Value: leftVal / rightVal}
+		case "//":
+			return &objects.Float{Value: math.Floor(leftVal / rightVal)}
+		default:
+			return &objects.Float{Value: leftVal - math.Floor(leftVal/rightVal)*rightVal}
+		}
+	case "**":
+		return &objects.Float{Value: math.Pow(leftVal, rightVal)}
 	case "<":
 		return toBool(leftVal < rightVal)
 	case ">":
@@ -419,20 +792,90 @@ func evalIntInfixExpression(
 		return toBool(leftVal != rightVal)
 
 	default:
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		return newTypeError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+// floorDiv divides rounding towards negative infinity, so that `%` (which is
+// defined in terms of it) takes the sign of the divisor.
+func floorDiv(a, b int64) int64 {
+	quotient := a / b
+	if (a%b != 0) && ((a < 0) != (b < 0)) {
+		quotient--
+	}
+	return quotient
+}
+
+// intPow raises base to a non-negative exponent by repeated squaring,
+// reporting whether the result fit in an int64.
+func intPow(base, exponent int64) (int64, bool) {
+	result, fits := int64(1), true
+	for exponent > 0 {
+		var ok bool
+		if exponent&1 == 1 {
+			result, ok = mulChecked(result, base)
+			fits = fits && ok
+		}
+		exponent >>= 1
+		if exponent > 0 {
+			base, ok = mulChecked(base, base)
+			fits = fits && ok
+		}
+	}
+	return result, fits
+}
+
+// mulChecked multiplies, reporting whether the product fit in an int64.
+func mulChecked(a, b int64) (int64, bool) {
+	product := a * b
+	if a == 0 || b == 0 {
+		return product, true
+	}
+	if (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		return product, false
+	}
+	return product, product/b == a
+}
+
+// evalOverflow redoes an int64 operation that overflowed with arbitrary
+// precision, or reports it in checked mode.
+func (in *Interpreter) evalOverflow(left int64, operator string, right int64) objects.Object {
+	if in.CheckedArithmetic {
+		return newError("integer overflow: %d %s %d", left, operator, right)
+	}
+
+	return evalBigInfixExpression(operator, big.NewInt(left), big.NewInt(right))
+}
+
+func isNumeric(obj objects.Object) bool {
+	return obj.Type() == objects.INTEGER_OBJ || obj.Type() == objects.FLOAT_OBJ
+}
+
+// toFloat widens a numeric object to a float64; callers check isNumeric first.
+func toFloat(obj objects.Object) float64 {
+	switch obj := obj.(type) {
+	case *objects.Integer:
+		return float64(obj.Value)
+	case *objects.BigInteger:
+		f, _ := new(big.Float).SetInt(obj.Value).Float64()
+		return f
+	case *objects.Float:
+		return obj.Value
+	default:
+		return 0
 	}
 }
 
-func evalIfExpression(ifExp *ast.IfExpression, machine *objects.Machine) objects.Object {
-	condition := Eval(ifExp.Condition, machine)
+func (in *Interpreter) evalIfExpression(ifExp *ast.IfExpression, machine *objects.Machine) objects.Object {
+	condition := in.Eval(ifExp.Condition, machine)
 
 	if isError(condition) {
 		return condition
 	}
 	if isTruthy(condition) {
-		return Eval(ifExp.Consequence, machine)
+		return in.Eval(ifExp.Consequence, machine)
 	} else if ifExp.Alternative != nil {
-		return Eval(ifExp.Alternative, machine)
+		return in.Eval(ifExp.Alternative, machine)
 	} else {
 		return NULL
 	}
@@ -0,0 +1,88 @@
+package evaluator
+
+import (
+	"sepia/ast"
+	"sepia/objects"
+)
+
+// The functions below expose the tree-walker's semantics to other execution
+// engines (see the vm package), so both agree on what every operator does.
+// Those depending on the settings of an Interpreter are its methods.
+
+// EvalInfix applies a binary operator to two already-evaluated operands.
+func (in *Interpreter) EvalInfix(operator string, left, right objects.Object) objects.Object {
+	return in.evalInfixExpression(operator, left, right)
+}
+
+// EvalPrefix applies a unary operator to an already-evaluated operand.
+func (in *Interpreter) EvalPrefix(operator string, right objects.Object) objects.Object {
+	return in.evalPrefixExpression(operator, right)
+}
+
+// EvalIndex indexes into an array or map.
+func EvalIndex(left, index objects.Object) objects.Object {
+	return evalIndexExpression(left, index)
+}
+
+// EvalTemplate joins the evaluated parts of an interpolated string.
+func EvalTemplate(parts []objects.Object) objects.Object {
+	return evalTemplate(parts)
+}
+
+// EvalMember looks up a field of a struct or a binding of a module.
+func EvalMember(object objects.Object, property string) objects.Object {
+	return evalMember(object, property)
+}
+
+// NewStructure builds the Structure a `structure` statement declares, or an
+// error if the statement is invalid.
+func NewStructure(node *ast.StructureStatement) objects.Object {
+	return newStructure(node)
+}
+
+// Instantiate makes a Struct from one value per field of its structure.
+func Instantiate(structure *objects.Structure, args []objects.Object) objects.Object {
+	return instantiate(structure, args)
+}
+
+// MatchPattern reports whether value matches pattern, returning the values
+// of the names it binds (in the order ast.PatternBindings lists them) and
+// TRUE, or FALSE, or an error.
+func (in *Interpreter) MatchPattern(pattern ast.Pattern, value objects.Object) ([]objects.Object, objects.Object) {
+	return in.matchPattern(pattern, value, nil)
+}
+
+// NoMatch is the error for a match expression none of whose arms matched subject.
+func NoMatch(subject objects.Object) *objects.Error {
+	return noMatch(subject)
+}
+
+// ResolveImportPath makes the path of an import absolute.
+func ResolveImportPath(node *ast.ImportExpression) (string, error) {
+	return resolveImportPath(node)
+}
+
+// ParseModule reads and parses the file at path, which node imports.
+func ParseModule(node *ast.ImportExpression, path string) (*ast.Program, *objects.Error) {
+	return parseModule(node, path)
+}
+
+// IsTruthy reports whether obj counts as true in a condition.
+func IsTruthy(obj objects.Object) bool {
+	return isTruthy(obj)
+}
+
+// LookupBuiltin finds a builtin function by name.
+func LookupBuiltin(name string) (*objects.Builtin, bool) {
+	builtin, ok := builtins[name]
+	return builtin, ok
+}
+
+// BuiltinNames lists the names of every builtin function.
+func BuiltinNames() []string {
+	names := make([]string, 0, len(builtins))
+	for name := range builtins {
+		names = append(names, name)
+	}
+	return names
+}
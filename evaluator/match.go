@@ -0,0 +1,147 @@
+package evaluator
+
+import (
+	"sepia/ast"
+	"sepia/objects"
+)
+
+// evalMatchExpression evaluates the body of the first arm whose pattern
+// matches the subject and whose guard, if any, holds. The names a pattern
+// binds are bound in machine, like `value` statements in an `if` block, but
+// only once the guard has held: the guard sees them in a scope of its own.
+func (in *Interpreter) evalMatchExpression(node *ast.MatchExpression, machine *objects.Machine, isTail bool) objects.Object {
+	subject := in.Eval(node.Subject, machine)
+	if isError(subject) {
+		return subject
+	}
+
+	for _, arm := range node.Arms {
+		values, result := in.matchPattern(arm.Pattern, subject, nil)
+		if isError(result) {
+			return withPosition(result, arm.Pattern)
+		}
+		if result != TRUE {
+			continue
+		}
+
+		names := ast.PatternBindings(arm.Pattern)
+		if arm.Guard != nil {
+			guardMachine := objects.NewLocalMachine(machine)
+			for i, name := range names {
+				guardMachine.Set(name.Value, values[i])
+			}
+
+			guard := in.Eval(arm.Guard, guardMachine)
+			if isError(guard) {
+				return guard
+			}
+			if !isTruthy(guard) {
+				continue
+			}
+		}
+
+		for i, name := range names {
+			if result := machine.Set(name.Value, values[i]); isError(result) {
+				return withPosition(result, name)
+			}
+			machine.SetType(name.Value, "")
+		}
+
+		return in.evalBlockTail(arm.Body, machine, isTail)
+	}
+
+	return noMatch(subject)
+}
+
+func noMatch(subject objects.Object) *objects.Error {
+	return newError("no pattern matches %s", subject.Inspect())
+}
+
+// matchPattern reports whether value matches pattern, returning TRUE or FALSE
+// or an error, along with bound: the values of the names the pattern binds,
+// in the order ast.PatternBindings lists them.
+func (in *Interpreter) matchPattern(pattern ast.Pattern, value objects.Object, bound []objects.Object) ([]objects.Object, objects.Object) {
+	switch pattern := pattern.(type) {
+	case *ast.LiteralPattern:
+		literal := in.Eval(pattern.Value, nil)
+		if isError(literal) {
+			return bound, literal
+		}
+		// Numbers match like `==`, so `1` matches 1.0 too.
+		if literal.Type() != value.Type() && !(isNumeric(literal) && isNumeric(value)) {
+			return bound, FALSE
+		}
+		// `==` compares strings and booleans by identity, so compare their
+		// values instead.
+		switch literal := literal.(type) {
+		case *objects.String:
+			return bound, toBool(literal.Value == value.(*objects.String).Value)
+		case *objects.Boolean:
+			return bound, toBool(literal.Value == value.(*objects.Boolean).Value)
+		}
+		return bound, in.evalInfixExpression("==", value, literal)
+	case *ast.BindingPattern:
+		if typeName := pattern.Name.TypeName(); typeName != "" && !objects.HasType(value, typeName) {
+			return bound, FALSE
+		}
+		if pattern.Name.Value != "_" {
+			bound = append(bound, value)
+		}
+		return bound, TRUE
+	case *ast.ArrayPattern:
+		array, ok := value.(*objects.Array)
+		if !ok || len(array.Elements) < len(pattern.Elements) ||
+			pattern.Rest == nil && len(array.Elements) != len(pattern.Elements) {
+			return bound, FALSE
+		}
+
+		for i, element := range pattern.Elements {
+			var result objects.Object
+			if bound, result = in.matchPattern(element, array.Elements[i], bound); result != TRUE {
+				return bound, result
+			}
+		}
+		if pattern.Rest != nil && pattern.Rest.Value != "_" {
+			rest := make([]objects.Object, len(array.Elements)-len(pattern.Elements))
+			copy(rest, array.Elements[len(pattern.Elements):])
+			bound = append(bound, &objects.Array{Elements: rest})
+		}
+		return bound, TRUE
+	case *ast.MapPattern:
+		m, ok := value.(*objects.Map)
+		if !ok {
+			return bound, FALSE
+		}
+
+		for i, key := range pattern.Keys {
+			pair, ok := m.Pairs[in.Eval(key, nil).(objects.Mappable).MapKey()]
+			if !ok {
+				return bound, FALSE
+			}
+			var result objects.Object
+			if bound, result = in.matchPattern(pattern.Values[i], pair.Value, bound); result != TRUE {
+				return bound, result
+			}
+		}
+		return bound, TRUE
+	case *ast.StructPattern:
+		instance, ok := value.(*objects.Struct)
+		if !ok || instance.Structure.Name != pattern.Name.Value {
+			return bound, FALSE
+		}
+
+		for i, field := range pattern.Fields {
+			fieldValue, ok := instance.Get(field.Value)
+			if !ok {
+				return bound, withPosition(newError("structure `%s` has no field `%s`", instance.Structure.Name, field.Value), field)
+			}
+			var result objects.Object
+			if bound, result = in.matchPattern(pattern.Values[i], fieldValue, bound); result != TRUE {
+				return bound, result
+			}
+		}
+		return bound, TRUE
+	default:
+		return bound, newError("unknown pattern: %s", pattern.String())
+	}
+}
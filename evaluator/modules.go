@@ -0,0 +1,86 @@
+package evaluator
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"sepia/ast"
+	"sepia/lexer"
+	"sepia/objects"
+	"sepia/parser"
+	"strings"
+)
+
+// ResetModules forgets every module imported so far, so that importing a file
+// again evaluates it afresh, picking up any changes to it.
+func (in *Interpreter) ResetModules() {
+	in.modules = map[string]*objects.Module{}
+}
+
+// resolveImportPath makes an import path absolute, treating relative paths as
+// relative to the directory of the importing file (or the working directory in the REPL).
+func resolveImportPath(node *ast.ImportExpression) (string, error) {
+	path := node.Path
+	if !filepath.IsAbs(path) && node.Token.Position.File != "" {
+		path = filepath.Join(filepath.Dir(node.Token.Position.File), path)
+	}
+
+	return filepath.Abs(path)
+}
+
+func (in *Interpreter) evalImportExpression(node *ast.ImportExpression) objects.Object {
+	path, err := resolveImportPath(node)
+	if err != nil {
+		return newError("could not resolve import %q: %s", node.Path, err)
+	}
+
+	if module, ok := in.modules[path]; ok {
+		return module
+	}
+
+	for idx, importing := range in.importStack {
+		if importing == path {
+			cycle := append(append([]string{}, in.importStack[idx:]...), path)
+			return newError("import cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+
+	program, parseErr := parseModule(node, path)
+	if parseErr != nil {
+		return parseErr
+	}
+
+	in.importStack = append(in.importStack, path)
+	defer func() { in.importStack = in.importStack[:len(in.importStack)-1] }()
+
+	module := &objects.Module{Path: path, Machine: objects.NewMachine()}
+	if result := in.Eval(program, module.Machine); isError(result) {
+		return result
+	}
+
+	in.modules[path] = module
+	return module
+}
+
+// parseModule reads and parses the file at path, which node imports.
+func parseModule(node *ast.ImportExpression, path string) (*ast.Program, *objects.Error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, newError("could not import %q: %s", node.Path, err)
+	}
+
+	p := parser.New(lexer.NewWithFile(string(data), path))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, newError("could not parse module %q: %s", node.Path, strings.Join(p.Errors(), "; "))
+	}
+	return program, nil
+}
+
+func (in *Interpreter) evalMemberExpression(node *ast.MemberExpression, machine *objects.Machine) objects.Object {
+	object := in.Eval(node.Object, machine)
+	if isError(object) {
+		return object
+	}
+
+	return evalMember(object, node.Property.Value)
+}
@@ -2,9 +2,31 @@ package evaluator
 
 import (
 	"fmt"
+	"math"
+	"math/big"
 	"sepia/objects"
+	"strconv"
+	"strings"
+	"unicode/utf8"
 )
 
+// stringify converts obj to a String for the `string` builtin and for
+// interpolation, or returns an error if it has no string form.
+func stringify(obj objects.Object) objects.Object {
+	switch obj := obj.(type) {
+	case *objects.String:
+		return obj
+	case *objects.Integer:
+		return &objects.String{Value: fmt.Sprintf("%v", obj.Value)}
+	case *objects.BigInteger, *objects.Float:
+		return &objects.String{Value: obj.Inspect()}
+	case *objects.Boolean:
+		return &objects.String{Value: fmt.Sprintf("%v", obj.Value)}
+	default:
+		return newError("argument to `string` not supported, got %s.", obj.Type())
+	}
+}
+
 var builtins = map[string]*objects.Builtin{
 	"len": &objects.Builtin{
 		Fn: func(args ...objects.Object) objects.Object {
@@ -13,7 +35,7 @@ var builtins = map[string]*objects.Builtin{
 			}
 			switch arg := args[0].(type) {
 			case *objects.String:
-				return &objects.Integer{Value: int64(len(arg.Value))}
+				return &objects.Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
 			case *objects.Array:
 				return &objects.Integer{Value: int64(len(arg.Elements))}
 			default:
@@ -37,24 +59,35 @@ var builtins = map[string]*objects.Builtin{
 			return NULL
 		},
 	},
-	"string": &objects.Builtin{
+	"exit": &objects.Builtin{
 		Fn: func(args ...objects.Object) objects.Object {
-			if len(args) != 1 {
-				return newError("Wrong number of arguments supplied. got=%d, want=1", len(args))
+			if len(args) > 1 {
+				return newError("Wrong number of arguments supplied. got=%d, want=0 or 1", len(args))
 			}
 
-			switch arg := args[0].(type) {
-			case *objects.String:
-				return arg
+			if len(args) == 0 {
+				return &objects.Exit{Code: 0}
+			}
+
+			switch code := args[0].(type) {
 			case *objects.Integer:
-				return &objects.String{Value: fmt.Sprintf("%v", arg.Value)}
-			case *objects.Boolean:
-				return &objects.String{Value: fmt.Sprintf("%v", arg.Value)}
+				return &objects.Exit{Code: int(code.Value)}
+			case *objects.BigInteger:
+				return newError("exit code out of range: %s.", code.Inspect())
 			default:
-				return newError("argument to `string` not supported, got %s.", args[0].Type())
+				return newError("argument to `exit` must be INTEGER, got %s.", args[0].Type())
 			}
 		},
 	},
+	"string": &objects.Builtin{
+		Fn: func(args ...objects.Object) objects.Object {
+			if len(args) != 1 {
+				return newError("Wrong number of arguments supplied. got=%d, want=1", len(args))
+			}
+
+			return stringify(args[0])
+		},
+	},
 	"bool": &objects.Builtin{
 		Fn: func(args ...objects.Object) objects.Object {
 			if len(args) != 1 {
@@ -63,9 +96,13 @@ var builtins = map[string]*objects.Builtin{
 
 			switch arg := args[0].(type) {
 			case *objects.String:
-				return &objects.Boolean{Value: arg.Value != ""}
+				return toBool(arg.Value != "")
 			case *objects.Integer:
-				return &objects.Boolean{Value: arg.Value != 0}
+				return toBool(arg.Value != 0)
+			case *objects.BigInteger:
+				return TRUE
+			case *objects.Float:
+				return toBool(arg.Value != 0)
 			case *objects.Boolean:
 				return arg
 			default:
@@ -81,9 +118,19 @@ var builtins = map[string]*objects.Builtin{
 
 			switch arg := args[0].(type) {
 			case *objects.String:
-				return &objects.Integer{Value: int64(len(arg.Value))}
-			case *objects.Integer:
+				// A string converts to its length in characters, like `len`.
+				return &objects.Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
+			case *objects.Integer, *objects.BigInteger:
 				return arg
+			case *objects.Float:
+				if math.IsNaN(arg.Value) || math.IsInf(arg.Value, 0) {
+					return newError("could not convert %s to INTEGER.", arg.Inspect())
+				}
+				if arg.Value >= -(1<<63) && arg.Value < 1<<63 {
+					return &objects.Integer{Value: int64(arg.Value)}
+				}
+				value, _ := big.NewFloat(arg.Value).Int(nil)
+				return objects.NewBigInteger(value)
 			case *objects.Boolean:
 				bitSet := arg.Value
 				bitSetVar := int64(0)
@@ -96,6 +143,33 @@ var builtins = map[string]*objects.Builtin{
 			}
 		},
 	},
+	"float": &objects.Builtin{
+		Fn: func(args ...objects.Object) objects.Object {
+			if len(args) != 1 {
+				return newError("Wrong number of arguments supplied. got=%d, want=1", len(args))
+			}
+
+			switch arg := args[0].(type) {
+			case *objects.String:
+				value, err := strconv.ParseFloat(strings.TrimSpace(arg.Value), 64)
+				if err != nil {
+					return newError("could not convert %q to FLOAT.", arg.Value)
+				}
+				return &objects.Float{Value: value}
+			case *objects.Integer, *objects.BigInteger:
+				return &objects.Float{Value: toFloat(arg)}
+			case *objects.Float:
+				return arg
+			case *objects.Boolean:
+				if arg.Value {
+					return &objects.Float{Value: 1}
+				}
+				return &objects.Float{Value: 0}
+			default:
+				return newError("argument to `float` not supported, got %s.", args[0].Type())
+			}
+		},
+	},
 	"first": &objects.Builtin{
 		Fn: func(args ...objects.Object) objects.Object {
 			if len(args) != 1 {
@@ -146,6 +220,58 @@ var builtins = map[string]*objects.Builtin{
 		},
 	},
 
+	"slice": &objects.Builtin{
+		Fn: func(args ...objects.Object) objects.Object {
+			if len(args) != 2 && len(args) != 3 {
+				return newError("wrong number of arguments. got=%d, want=2 or 3", len(args))
+			}
+
+			var length int
+			var runes []rune
+			switch arg := args[0].(type) {
+			case *objects.String:
+				runes = []rune(arg.Value)
+				length = len(runes)
+			case *objects.Array:
+				length = len(arg.Elements)
+			default:
+				return newError("argument to `slice` must be STRING or ARRAY, got %s", args[0].Type())
+			}
+
+			bounds := []int{0, length}
+			for i, arg := range args[1:] {
+				switch arg := arg.(type) {
+				case *objects.Integer:
+					bounds[i] = int(arg.Value)
+					if arg.Value < 0 {
+						bounds[i] = 0
+					} else if arg.Value > int64(length) {
+						bounds[i] = length
+					}
+				case *objects.BigInteger:
+					if arg.Value.Sign() > 0 {
+						bounds[i] = length
+					} else {
+						bounds[i] = 0
+					}
+				default:
+					return newError("bounds of `slice` must be INTEGER, got %s", arg.Type())
+				}
+			}
+			start, end := bounds[0], bounds[1]
+			if end < start {
+				end = start
+			}
+
+			if runes != nil {
+				return &objects.String{Value: string(runes[start:end])}
+			}
+			elements := make([]objects.Object, end-start)
+			copy(elements, args[0].(*objects.Array).Elements[start:end])
+			return &objects.Array{Elements: elements}
+		},
+	},
+
 	"rest": &objects.Builtin{
 		Fn: func(args ...objects.Object) objects.Object {
 			if len(args) != 1 {
@@ -0,0 +1,49 @@
+package evaluator
+
+import (
+	"sepia/ast"
+	"sepia/objects"
+	"sepia/util"
+)
+
+// newStructure builds the Structure a `structure` statement declares.
+func newStructure(node *ast.StructureStatement) objects.Object {
+	if objects.IsBuiltinType(node.Name.Value) {
+		return newError("cannot name a structure `%s`, which is a builtin type", node.Name.Value)
+	}
+
+	structure := &objects.Structure{Name: node.Name.Value}
+	for _, field := range node.Fields {
+		structure.Fields = append(structure.Fields, field.Value)
+	}
+	return structure
+}
+
+// instantiate makes a Struct from one value per field of its structure.
+func instantiate(structure *objects.Structure, args []objects.Object) objects.Object {
+	if len(args) != len(structure.Fields) {
+		return newError("structure `%s` expects %s, got %d", structure.Name, util.Plural(len(structure.Fields), "field"), len(args))
+	}
+
+	values := make([]objects.Object, len(args))
+	copy(values, args)
+	return &objects.Struct{Structure: structure, Values: values}
+}
+
+// evalMember looks up the binding a module exports or a field of a struct.
+func evalMember(object objects.Object, property string) objects.Object {
+	switch object := object.(type) {
+	case *objects.Module:
+		if val, ok := object.Machine.GetLocal(property); ok {
+			return val
+		}
+		return newError("module %q has no binding `%s`", object.Path, property)
+	case *objects.Struct:
+		if val, ok := object.Get(property); ok {
+			return val
+		}
+		return newError("structure `%s` has no field `%s`", object.Structure.Name, property)
+	default:
+		return newError("member access not supported: %s.%s", object.Type(), property)
+	}
+}
@@ -0,0 +1,96 @@
+package evaluator
+
+import (
+	"sepia/ast"
+	"sepia/objects"
+	"sepia/token"
+)
+
+// tailCall is a call in tail position that has been evaluated up to, but not
+// including, applying the function. It only ever travels from evalBlockTail
+// back to the trampoline in applyFunction.
+type tailCall struct {
+	function *objects.Function
+	args     []objects.Object
+	callSite token.Position
+}
+
+func (tc *tailCall) Type() objects.ObjectType { return "TAIL_CALL" }
+func (tc *tailCall) Inspect() string          { return "tail call to " + functionName(tc.function) }
+
+// evalBlockTail evaluates a function body (or a block nested in one). The
+// final statement is in tail position when isTail is set; return statements
+// always are, since they leave the function.
+func (in *Interpreter) evalBlockTail(block *ast.BlockStatement, machine *objects.Machine, isTail bool) objects.Object {
+	var result objects.Object
+
+	for idx, statement := range block.Statements {
+		result = in.evalStatementTail(statement, machine, isTail && idx == len(block.Statements)-1)
+
+		if result != nil {
+			rt := result.Type()
+			if rt == objects.RETURN_VALUE_OBJ || rt == objects.ERROR_OBJ || rt == objects.EXIT_OBJ {
+				return result
+			}
+		}
+	}
+
+	return result
+}
+
+func (in *Interpreter) evalStatementTail(statement ast.Statement, machine *objects.Machine, isTail bool) objects.Object {
+	switch statement := statement.(type) {
+	case *ast.ReturnStatement:
+		val := in.evalExpressionTail(statement.ReturnValue, machine, true)
+		if isError(val) {
+			return val
+		}
+		return &objects.ReturnValue{Value: val}
+	case *ast.ExpressionStatement:
+		return in.evalExpressionTail(statement.Expression, machine, isTail)
+	default:
+		return in.Eval(statement, machine)
+	}
+}
+
+func (in *Interpreter) evalExpressionTail(expression ast.Expression, machine *objects.Machine, isTail bool) objects.Object {
+	switch expression := expression.(type) {
+	case *ast.IfExpression:
+		condition := in.Eval(expression.Condition, machine)
+		if isError(condition) {
+			return condition
+		}
+
+		if isTruthy(condition) {
+			return in.evalBlockTail(expression.Consequence, machine, isTail)
+		} else if expression.Alternative != nil {
+			return in.evalBlockTail(expression.Alternative, machine, isTail)
+		}
+		return NULL
+	case *ast.MatchExpression:
+		return withPosition(in.evalMatchExpression(expression, machine, isTail), expression)
+	case *ast.CallExpression:
+		return withPosition(in.evalCallExpression(expression, machine, isTail), expression)
+	default:
+		return in.Eval(expression, machine)
+	}
+}
+
+// evalCallExpression evaluates a call. In tail position, calls to Sepia
+// functions are handed back as a tailCall for the caller's trampoline to run.
+func (in *Interpreter) evalCallExpression(node *ast.CallExpression, machine *objects.Machine, isTail bool) objects.Object {
+	function := in.Eval(node.Function, machine)
+	if isError(function) {
+		return function
+	}
+	args := in.evalExpressions(node.Arguments, machine)
+	if len(args) == 1 && isError(args[0]) {
+		return args[0]
+	}
+
+	if fn, ok := function.(*objects.Function); ok && isTail {
+		return &tailCall{function: fn, args: args, callSite: node.Function.Pos()}
+	}
+
+	return in.applyFunction(function, args, node.Function.Pos())
+}
@@ -3,26 +3,48 @@ package lexer
 import (
 	"sepia/token"
 	"sepia/util"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 // Lexer is a structure that lexes a given input.
 type Lexer struct {
 	input           string
-	position        int
-	readingPosition int
-	currentChar     byte
+	position        int  // byte offset of currentChar
+	readingPosition int  // byte offset of the character after it
+	currentChar     rune // decoded from UTF-8; invalid bytes come through as utf8.RuneError
+
+	file   string
+	line   int
+	column int
+
+	// interpolations holds, for each `{` interpolation we are inside, how
+	// many braces are open within it.
+	interpolations []int
 }
 
 func (lexer *Lexer) consumeChar() {
+	if lexer.readingPosition > len(lexer.input) {
+		return // already sitting on EOF
+	}
+
+	if lexer.currentChar == '\n' {
+		lexer.line++
+		lexer.column = 0
+	}
+	lexer.column++
+
+	width := 1
 	if lexer.readingPosition >= len(lexer.input) {
 		lexer.currentChar = 0
 	} else {
-		lexer.currentChar = lexer.input[lexer.readingPosition]
+		lexer.currentChar, width = utf8.DecodeRuneInString(lexer.input[lexer.readingPosition:])
 	}
 
 	lexer.position = lexer.readingPosition
-	lexer.readingPosition++
-
+	lexer.readingPosition += width
 }
 
 // NextToken get the next token.
@@ -31,6 +53,8 @@ func (lexer *Lexer) NextToken() token.Token {
 
 	lexer.skipWhitespace()
 
+	position := lexer.currentPosition()
+
 	switch lexer.currentChar {
 
 	// One-character bytes
@@ -55,12 +79,23 @@ func (lexer *Lexer) NextToken() token.Token {
 			t = token.Token{Type: token.INCREMENT,
 				Literal: string(character) + string(lexer.currentChar)}
 		default:
-			t = newToken(token.MINUS, lexer.currentChar)
+			t = newToken(token.PLUS, lexer.currentChar)
 		}
 	case '{':
 		t = newToken(token.LBRACE, lexer.currentChar)
+		if open := len(lexer.interpolations); open > 0 {
+			lexer.interpolations[open-1]++
+		}
 	case '}':
 		t = newToken(token.RBRACE, lexer.currentChar)
+		if open := len(lexer.interpolations); open > 0 {
+			if lexer.interpolations[open-1] == 0 {
+				lexer.interpolations = lexer.interpolations[:open-1]
+				t.Type, t.Literal = lexer.readString(true)
+				break
+			}
+			lexer.interpolations[open-1]--
+		}
 	case '[':
 		t = newToken(token.LBRACKET, lexer.currentChar)
 	case ']':
@@ -95,6 +130,12 @@ func (lexer *Lexer) NextToken() token.Token {
 
 	case '/':
 		switch lexer.peekCharacter() {
+		case '/':
+			character := lexer.currentChar
+			lexer.consumeChar()
+
+			t = token.Token{Type: token.FLOORDIV,
+				Literal: string(character) + string(lexer.currentChar)}
 		case '=':
 			character := lexer.currentChar
 			lexer.consumeChar()
@@ -104,8 +145,16 @@ func (lexer *Lexer) NextToken() token.Token {
 		default:
 			t = newToken(token.SLASH, lexer.currentChar)
 		}
+	case '%':
+		t = newToken(token.PERCENT, lexer.currentChar)
 	case '*':
 		switch lexer.peekCharacter() {
+		case '*':
+			character := lexer.currentChar
+			lexer.consumeChar()
+
+			t = token.Token{Type: token.POWER,
+				Literal: string(character) + string(lexer.currentChar)}
 		case '=':
 			character := lexer.currentChar
 			lexer.consumeChar()
@@ -137,12 +186,20 @@ func (lexer *Lexer) NextToken() token.Token {
 				Literal: string(character) + string(lexer.currentChar),
 			}
 		} else {
-			t = newToken(token.GTEQ, lexer.currentChar)
+			t = newToken(token.GT, lexer.currentChar)
 		}
 	case ';':
 		t = newToken(token.SEMICOLON, lexer.currentChar)
 	case ':':
 		t = newToken(token.COLON, lexer.currentChar)
+	case '.':
+		if lexer.peekCharacterAt(1) == '.' && lexer.peekCharacterAt(2) == '.' {
+			lexer.consumeChar()
+			lexer.consumeChar()
+			t = token.Token{Type: token.ELLIPSIS, Literal: "..."}
+		} else {
+			t = newToken(token.DOT, lexer.currentChar)
+		}
 
 	case '#':
 		for lexer.peekCharacter() != '\n' && lexer.peekCharacter() != 0 {
@@ -214,17 +271,19 @@ func (lexer *Lexer) NextToken() token.Token {
 		}
 
 	case '"':
-		t.Type = token.STRING
-		t.Literal = lexer.readString()
+		t.Type, t.Literal = lexer.readString(false)
+	case '`':
+		t.Type, t.Literal = lexer.readRawString()
 
 	default:
 		if util.IsLetter(lexer.currentChar) {
 			t.Literal = lexer.consumeIdentifier()
 			t.Type = token.LookupIdent(t.Literal)
+			t.Position = position
 			return t
 		} else if util.IsDigit(lexer.currentChar) {
-			t.Literal = lexer.consumeInteger()
-			t.Type = token.INT
+			t.Literal, t.Type = lexer.consumeNumber()
+			t.Position = position
 			return t
 		} else {
 			t = newToken(token.ILLEGAL, lexer.currentChar)
@@ -232,61 +291,266 @@ func (lexer *Lexer) NextToken() token.Token {
 	}
 	lexer.consumeChar()
 
+	t.Position = position
 	return t
 }
 
+func (lexer *Lexer) currentPosition() token.Position {
+	return token.Position{File: lexer.file, Line: lexer.line, Column: lexer.column}
+}
+
 func (lexer *Lexer) skipWhitespace() {
 	for util.IsWhitespace(lexer.currentChar) {
 		lexer.consumeChar()
 	}
 }
 
-func (lexer *Lexer) consumeInteger() string {
+func (lexer *Lexer) consumeNumber() (string, token.Type) {
 	position := lexer.position
-	for util.IsDigit(lexer.currentChar) {
+	tokenType := token.Type(token.INT)
+
+	lexer.consumeDigits()
+
+	// A fractional part needs at least one digit after the dot, so `1.` stays an integer.
+	if lexer.currentChar == '.' && util.IsDigit(lexer.peekCharacter()) {
+		tokenType = token.FLOAT
 		lexer.consumeChar()
+		lexer.consumeDigits()
 	}
 
-	return lexer.input[position:lexer.position]
+	if lexer.currentChar == 'e' || lexer.currentChar == 'E' {
+		offset := 1
+		if sign := lexer.peekCharacterAt(1); sign == '+' || sign == '-' {
+			offset = 2
+		}
+
+		if util.IsDigit(lexer.peekCharacterAt(offset)) {
+			tokenType = token.FLOAT
+			for i := 0; i < offset; i++ {
+				lexer.consumeChar()
+			}
+			lexer.consumeDigits()
+		}
+	}
+
+	return lexer.input[position:lexer.position], tokenType
+}
+
+func (lexer *Lexer) consumeDigits() {
+	for util.IsDigit(lexer.currentChar) {
+		lexer.consumeChar()
+	}
 }
 
+// consumeIdentifier reads a letter or underscore followed by any number of
+// letters, digits and underscores, where letters and digits may be any Unicode ones.
 func (lexer *Lexer) consumeIdentifier() string {
 	position := lexer.position
-	for util.IsLetter(lexer.currentChar) {
+	for util.IsLetter(lexer.currentChar) || unicode.IsDigit(lexer.currentChar) {
 		lexer.consumeChar()
 	}
 
 	return lexer.input[position:lexer.position]
 }
 
-func (lexer *Lexer) peekCharacter() byte {
-	if lexer.readingPosition >= len(lexer.input) {
+func (lexer *Lexer) peekCharacter() rune {
+	return lexer.peekCharacterAt(1)
+}
+
+// peekCharacterAt looks n characters past the current one without consuming anything.
+func (lexer *Lexer) peekCharacterAt(n int) rune {
+	offset := lexer.position
+	for ; n > 0 && offset < len(lexer.input); n-- {
+		_, width := utf8.DecodeRuneInString(lexer.input[offset:])
+		offset += width
+	}
+
+	if offset >= len(lexer.input) {
 		return 0 // this will trigger an EOF
-	} else {
-		return lexer.input[lexer.readingPosition] // Recall that readingPosition is always one character ahead of the pos
 	}
+
+	character, _ := utf8.DecodeRuneInString(lexer.input[offset:])
+	return character
 }
 
-func newToken(tokenType token.Type, character byte) token.Token {
+func newToken(tokenType token.Type, character rune) token.Token {
 	return token.Token{Type: tokenType, Literal: string(character)}
 }
 
-func (lexer *Lexer) readString() string {
-	position := lexer.position + 1
+// readString reads up to the closing quote, decoding escape sequences. A
+// string with no closing quote comes back as an ILLEGAL token holding the
+// opening quote and the rest of the input; one with an invalid escape comes
+// back as an ILLEGAL token holding the first bad escape.
+//
+// An unescaped `{` ends the token early as a TEMPLATE_HEAD, or as a
+// TEMPLATE_MIDDLE if resuming is set because we are picking the string back
+// up after an interpolation. NextToken resumes the string at the matching
+// `}`, which then ends in a TEMPLATE_TAIL.
+func (lexer *Lexer) readString(resuming bool) (token.Type, string) {
+	start := lexer.position
+	var out strings.Builder
+	invalid := ""
+
 	for {
 		lexer.consumeChar()
 
-		if lexer.currentChar == '"' || lexer.currentChar == 0 {
-			break
+		switch lexer.currentChar {
+		case 0:
+			return token.ILLEGAL, "\"" + lexer.input[start+1:lexer.position]
+		case '"', '{':
+			if invalid != "" {
+				return token.ILLEGAL, invalid
+			}
+
+			if lexer.currentChar == '{' {
+				lexer.interpolations = append(lexer.interpolations, 0)
+				if resuming {
+					return token.TEMPLATE_MIDDLE, out.String()
+				}
+				return token.TEMPLATE_HEAD, out.String()
+			}
+
+			if resuming {
+				return token.TEMPLATE_TAIL, out.String()
+			}
+			return token.STRING, out.String()
+		case '\\':
+			lexer.consumeChar()
+			if lexer.currentChar == 0 {
+				return token.ILLEGAL, "\"" + lexer.input[start+1:lexer.position]
+			}
+
+			decoded, ok := lexer.readEscape()
+			if !ok && invalid == "" {
+				invalid = decoded
+			}
+			out.WriteString(decoded)
+		default:
+			out.WriteString(lexer.input[lexer.position:lexer.readingPosition])
 		}
 	}
+}
 
-	return lexer.input[position:lexer.position]
+// readRawString reads a backtick-delimited string, which may span lines and
+// has no escapes or interpolation. The result is dedented: see dedent. A raw
+// string with no closing backtick comes back as an ILLEGAL token holding the
+// opening backtick and the rest of the input.
+func (lexer *Lexer) readRawString() (token.Type, string) {
+	start := lexer.position
+	for {
+		lexer.consumeChar()
+
+		switch lexer.currentChar {
+		case 0:
+			return token.ILLEGAL, lexer.input[start:lexer.position]
+		case '`':
+			return token.STRING, dedent(lexer.input[start+1 : lexer.position])
+		}
+	}
+}
+
+// dedent lets a multi-line raw string be indented along with the code around
+// it. If the string starts with a line break, that line break is dropped, as
+// is a last line holding nothing but indentation before the closing backtick.
+// Then the indentation shared by every line with any text on it is removed
+// from all lines. A raw string on a single line is left alone.
+func dedent(raw string) string {
+	if !strings.HasPrefix(raw, "\n") {
+		return raw
+	}
+
+	lines := strings.Split(raw[1:], "\n")
+	if last := lines[len(lines)-1]; strings.TrimLeft(last, " \t") == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	indent, found := "", false
+	for _, line := range lines {
+		text := strings.TrimLeft(line, " \t")
+		if text == "" {
+			continue
+		}
+
+		lineIndent := line[:len(line)-len(text)]
+		if !found {
+			indent, found = lineIndent, true
+		}
+		for !strings.HasPrefix(lineIndent, indent) {
+			indent = indent[:len(indent)-1]
+		}
+	}
+
+	for i, line := range lines {
+		if strings.HasPrefix(indent, line) {
+			lines[i] = "" // a blank line, perhaps with less indentation than the rest
+		} else {
+			lines[i] = strings.TrimPrefix(line, indent)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+var escapes = map[rune]string{
+	'n':  "\n",
+	't':  "\t",
+	'r':  "\r",
+	'0':  "\x00",
+	'\\': "\\",
+	'"':  "\"",
+	'{':  "{",
+}
+
+// readEscape decodes the escape sequence whose first character, after the
+// backslash, is the current one. \uXXXX and \u{X...} name a code point in hex.
+// If the escape is invalid, it returns its source text and false.
+func (lexer *Lexer) readEscape() (string, bool) {
+	if decoded, ok := escapes[lexer.currentChar]; ok {
+		return decoded, true
+	}
+
+	if lexer.currentChar != 'u' {
+		return "\\" + string(lexer.currentChar), false
+	}
+
+	start := lexer.position - 1
+	digits := ""
+	if lexer.peekCharacter() == '{' {
+		lexer.consumeChar()
+		for util.IsHexDigit(lexer.peekCharacter()) {
+			lexer.consumeChar()
+			digits += string(lexer.currentChar)
+		}
+		if lexer.peekCharacter() != '}' || digits == "" || len(digits) > 6 {
+			return lexer.input[start : lexer.position+1], false
+		}
+		lexer.consumeChar()
+	} else {
+		for i := 0; i < 4; i++ {
+			if !util.IsHexDigit(lexer.peekCharacter()) {
+				return lexer.input[start : lexer.position+1], false
+			}
+			lexer.consumeChar()
+			digits += string(lexer.currentChar)
+		}
+	}
+
+	code, _ := strconv.ParseUint(digits, 16, 32)
+	if code > unicode.MaxRune || code >= 0xD800 && code <= 0xDFFF {
+		return lexer.input[start : lexer.position+1], false
+	}
+
+	return string(rune(code)), true
 }
 
 // New creates a new Lexer and returns a reference to it.
 func New(input string) *Lexer {
-	lexer := Lexer{input: input}
+	return NewWithFile(input, "")
+}
+
+// NewWithFile creates a new Lexer whose token positions are reported against file.
+func NewWithFile(input string, file string) *Lexer {
+	lexer := Lexer{input: input, file: file, line: 1}
 	lexer.consumeChar()
 	return &lexer
 }
@@ -1,16 +1,30 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
 	"os/user"
+	"sepia/ast"
+	"sepia/compiler"
 	"sepia/evaluator"
 	"sepia/lexer"
 	"sepia/objects"
 	"sepia/parser"
 	"sepia/repl"
+	"sepia/typecheck"
+	"sepia/vm"
+	"time"
+)
+
+// Exit codes used when running a file. Scripts can pick any other code with `exit(code)`.
+const (
+	exitRuntimeError = 1
+	exitParseError   = 2
+	exitFileError    = 3
+	exitTypeError    = 4
 )
 
 func check(e error) {
@@ -19,33 +33,174 @@ func check(e error) {
 	}
 }
 
+var (
+	engine     = flag.String("engine", "eval", "how to run files: `eval` walks the syntax tree, `vm` compiles to bytecode")
+	benchmarks = flag.Int("bench", 0, "run the file `n` times with each engine and report timings instead of running it once")
+	maxDepth   = flag.Int("max-depth", evaluator.DefaultMaxCallDepth, "maximum depth of nested Sepia function calls (0 for no limit)")
+	checked    = flag.Bool("checked", false, "report integer overflow as a runtime error instead of promoting to a big integer")
+)
+
+// newInterpreter makes an Interpreter with the settings given on the command line.
+func newInterpreter() *evaluator.Interpreter {
+	interpreter := evaluator.New()
+	interpreter.MaxCallDepth = *maxDepth
+	interpreter.CheckedArithmetic = *checked
+	return interpreter
+}
+
 func main() {
-	if len(os.Args) == 1 {
+	flag.Parse()
+
+	if flag.Arg(0) == "check" {
+		if flag.NArg() != 2 {
+			fmt.Fprintln(os.Stderr, "❌ usage: sepia check file.sp")
+			os.Exit(exitFileError)
+		}
+		os.Exit(checkFile(flag.Arg(1)))
+	}
+
+	if flag.NArg() == 0 {
 		user, err := user.Current()
 		check(err)
 
 		fmt.Printf("Hello %s! Welcome to the Sepia programming language.\n", user.Username)
 
-		repl.Start(os.Stdin, os.Stdout)
+		repl.Start(os.Stdin, os.Stdout, newInterpreter())
 	} else {
-		machine := objects.NewMachine()
-		file := os.Args[1]
+		os.Exit(runFile(flag.Arg(0)))
+	}
+}
+
+// parseFile reads and parses a Sepia source file. If it can't, it reports why
+// and returns a nil program and the process exit code.
+func parseFile(file string) (*ast.Program, int) {
+	_data, err := ioutil.ReadFile(file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %s\n", err)
+		return nil, exitFileError
+	}
+	data := string(_data)
+
+	l := lexer.NewWithFile(data, file)
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		printParserErrors(os.Stderr, p.Errors())
+		return nil, exitParseError
+	}
+
+	return program, 0
+}
+
+// runFile evaluates a Sepia source file and returns the process exit code.
+func runFile(file string) int {
+	program, code := parseFile(file)
+	if program == nil {
+		return code
+	}
+
+	if *benchmarks > 0 {
+		return benchmark(program, *benchmarks)
+	}
 
-		_data, err := ioutil.ReadFile(file)
+	var evaluated objects.Object
+
+	switch *engine {
+	case "eval":
+		evaluated = newInterpreter().RunFile(program, objects.NewMachine(), file)
+	case "vm":
+		c := compiler.New()
+		if err := c.Compile(program); err != nil {
+			printCompileError(os.Stderr, err)
+			return exitParseError
+		}
+		evaluated = vm.New(c.Bytecode(), newInterpreter()).Run()
+	default:
+		fmt.Fprintf(os.Stderr, "❌ unknown engine %q, want eval or vm\n", *engine)
+		return exitFileError
+	}
+
+	switch evaluated := evaluated.(type) {
+	case *objects.Exit:
+		return evaluated.Code
+	case *objects.Error:
+		_, err := io.WriteString(os.Stderr, "❌ RUNTIME "+evaluated.Inspect()+"\n"+evaluated.StackTrace())
+		check(err)
+		return exitRuntimeError
+	}
+
+	return 0
+}
+
+// checkFile reports the type errors in a Sepia source file without running
+// it, and returns the process exit code.
+func checkFile(file string) int {
+	program, code := parseFile(file)
+	if program == nil {
+		return code
+	}
+
+	errors := typecheck.Check(program)
+	for _, typeErr := range errors {
+		_, err := io.WriteString(os.Stderr, "❌ TYPE ERROR: "+typeErr.Error()+"\n")
 		check(err)
-		data := string(_data)
+	}
+
+	if len(errors) != 0 {
+		return exitTypeError
+	}
+	return 0
+}
+
+// benchmark runs program n times with the tree-walking evaluator and n times
+// on the bytecode vm, printing the average time per run for each. Anything the
+// program prints is discarded while it is being timed. A run that fails stops
+// the benchmark, so a broken program isn't timed as a fast one.
+func benchmark(program *ast.Program, n int) int {
+	c := compiler.New()
+	if err := c.Compile(program); err != nil {
+		printCompileError(os.Stderr, err)
+		return exitParseError
+	}
+	bytecode := c.Bytecode()
 
-		l := lexer.New(data)
-		p := parser.New(l)
-		program := p.ParseProgram()
+	stdout := os.Stdout
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	check(err)
+	defer devNull.Close()
 
-		if len(p.Errors()) != 0 {
-			printParserErrors(os.Stdout, p.Errors())
-			return
+	timings := []struct {
+		name string
+		run  func() objects.Object
+	}{
+		{"eval", func() objects.Object { return newInterpreter().Run(program, objects.NewMachine()) }},
+		{"vm", func() objects.Object { return vm.New(bytecode, newInterpreter()).Run() }},
+	}
+
+	for _, timing := range timings {
+		os.Stdout = devNull
+		start := time.Now()
+		for i := 0; i < n; i++ {
+			if err, ok := timing.run().(*objects.Error); ok {
+				os.Stdout = stdout
+				_, writeErr := io.WriteString(os.Stderr, "❌ RUNTIME "+err.Inspect()+"\n"+err.StackTrace())
+				check(writeErr)
+				return exitRuntimeError
+			}
 		}
+		elapsed := time.Since(start)
+		os.Stdout = stdout
 
-		evaluator.Eval(program, machine)
+		fmt.Printf("%-5s %d runs, %v per run\n", timing.name, n, elapsed/time.Duration(n))
 	}
+
+	return 0
+}
+
+func printCompileError(out io.Writer, compileErr error) {
+	_, err := io.WriteString(out, "❌ COMPILE ERROR: "+compileErr.Error()+"\n")
+	check(err)
 }
 
 func printParserErrors(out io.Writer, errors []string) {
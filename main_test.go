@@ -0,0 +1,185 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sepia/ast"
+	"sepia/compiler"
+	"sepia/evaluator"
+	"sepia/lexer"
+	"sepia/objects"
+	"sepia/parser"
+	"sepia/vm"
+	"sync"
+	"testing"
+)
+
+// engines runs a program with each engine, returning its result.
+var engines = []struct {
+	name string
+	run  func(t testing.TB, program *ast.Program) objects.Object
+}{
+	{"eval", func(t testing.TB, program *ast.Program) objects.Object {
+		return evaluator.New().Run(program, objects.NewMachine())
+	}},
+	{"vm", func(t testing.TB, program *ast.Program) objects.Object {
+		c := compiler.New()
+		if err := c.Compile(program); err != nil {
+			t.Fatalf("compile error: %s", err)
+		}
+		return vm.New(c.Bytecode(), evaluator.New()).Run()
+	}},
+}
+
+func parse(t testing.TB, file, source string) *ast.Program {
+	p := parser.New(lexer.NewWithFile(source, file))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parse errors in %s: %v", file, p.Errors())
+	}
+	return program
+}
+
+// capture runs run with os.Stdout sent to a temporary file, returning what was
+// printed.
+func capture(t testing.TB, run func()) string {
+	out, err := ioutil.TempFile("", "sepia-stdout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(out.Name())
+	defer out.Close()
+
+	stdout := os.Stdout
+	os.Stdout = out
+	defer func() { os.Stdout = stdout }()
+	run()
+
+	printed, err := ioutil.ReadFile(out.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(printed)
+}
+
+// parityCases are programs that aren't examples but must still behave the
+// same on both engines, such as those failing partway through.
+var parityCases = map[string]string{
+	"update-constant-in-function.sp": `
+constant greeting = "Hello"
+print(greeting)
+
+value rename = f() ->
+    update greeting = "Hi"
+end
+
+print("rename is only an error once it's called")
+rename()
+`,
+	"update-constant.sp": `
+constant total = 1
+print(total)
+total += 1
+`,
+	"redefine-constant.sp": `
+constant greeting = "Hello"
+print(greeting)
+value greeting = "Hi"
+`,
+	"bind-constant.sp": `
+constant n = 1
+print(match (2) -> n -> n end end)
+`,
+}
+
+// TestExamplesParity runs every example, and every parity case, on both
+// engines, which must print the same output and fail, if at all, with the same
+// error.
+func TestExamplesParity(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("examples", "*.sp"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no examples found")
+	}
+
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			source, err := ioutil.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkParity(t, file, string(source))
+		})
+	}
+	for file, source := range parityCases {
+		t.Run(file, func(t *testing.T) { checkParity(t, file, source) })
+	}
+}
+
+func checkParity(t *testing.T, file, source string) {
+	outputs := make([]string, len(engines))
+	for i, engine := range engines {
+		program := parse(t, file, source)
+		outputs[i] = capture(t, func() {
+			if err, ok := engine.run(t, program).(*objects.Error); ok {
+				os.Stdout.WriteString("ERROR: " + err.Inspect() + "\n" + err.StackTrace())
+			}
+		})
+	}
+
+	if outputs[0] != outputs[1] {
+		t.Errorf("%s printed:\n%s\n%s printed:\n%s", engines[0].name, outputs[0], engines[1].name, outputs[1])
+	}
+}
+
+const fibonacci = `
+value fibonacci = f(n) ->
+    if (n < 2) ->
+        return n;
+    end
+    fibonacci(n - 1) + fibonacci(n - 2)
+end
+
+fibonacci(20)
+`
+
+// TestConcurrentRun runs programs from several goroutines at once, each with
+// an Interpreter of its own, so they must not corrupt each other's call stacks.
+func TestConcurrentRun(t *testing.T) {
+	program := parse(t, "fib.sp", fibonacci)
+
+	var wg sync.WaitGroup
+	results := make([]objects.Object, 4)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = evaluator.New().Run(program, objects.NewMachine())
+		}(i)
+	}
+	wg.Wait()
+
+	for _, result := range results {
+		if integer, ok := result.(*objects.Integer); !ok || integer.Value != 6765 {
+			t.Errorf("fibonacci(20) = %s, want 6765", result.Inspect())
+		}
+	}
+}
+
+func benchmarkFib(b *testing.B, engine int) {
+	program := parse(b, "fib.sp", fibonacci)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		result := engines[engine].run(b, program)
+		if integer, ok := result.(*objects.Integer); !ok || integer.Value != 6765 {
+			b.Fatalf("fibonacci(20) = %s, want 6765", result.Inspect())
+		}
+	}
+}
+
+func BenchmarkFibEval(b *testing.B) { benchmarkFib(b, 0) }
+func BenchmarkFibVM(b *testing.B)   { benchmarkFib(b, 1) }
@@ -4,7 +4,13 @@ import (
 	"bytes"
 	"fmt"
 	"hash/fnv"
+	"math"
+	"math/big"
 	"sepia/ast"
+	"sepia/token"
+	"sepia/util"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -12,17 +18,32 @@ type ObjectType string
 
 const (
 	INTEGER_OBJ      = "INTEGER"
+	FLOAT_OBJ        = "FLOAT"
 	BOOLEAN_OBJ      = "BOOLEAN"
 	NULL_OBJ         = "NULL"
 	RETURN_VALUE_OBJ = "RETURN_VALUE"
 	ERROR_OBJ        = "ERROR"
+	EXIT_OBJ         = "EXIT"
 	FUNCTION_OBJ     = "FUNCTION"
 	STRING_OBJ       = "STRING"
 	BUILTIN_OBJ      = "BUILTIN"
 	ARRAY_OBJ        = "ARRAY"
 	MAP_OBJ          = "MAP"
+	MODULE_OBJ       = "MODULE"
+	STRUCTURE_OBJ    = "STRUCTURE"
 )
 
+// IsBuiltinType reports whether name is the type of a builtin object, which a
+// structure is not allowed to be named after.
+func IsBuiltinType(name string) bool {
+	switch ObjectType(name) {
+	case INTEGER_OBJ, FLOAT_OBJ, BOOLEAN_OBJ, NULL_OBJ, RETURN_VALUE_OBJ, ERROR_OBJ, EXIT_OBJ,
+		FUNCTION_OBJ, STRING_OBJ, BUILTIN_OBJ, ARRAY_OBJ, MAP_OBJ, MODULE_OBJ, STRUCTURE_OBJ:
+		return true
+	}
+	return false
+}
+
 type BuiltinFunc func(args ...Object) Object
 
 type Builtin struct {
@@ -44,6 +65,37 @@ type Integer struct {
 func (i *Integer) Inspect() string  { return fmt.Sprintf("%d", i.Value) }
 func (i *Integer) Type() ObjectType { return INTEGER_OBJ }
 
+// BigInteger is an INTEGER too large for int64. Arithmetic promotes to it on
+// overflow and NewBigInteger demotes results back to an Integer when they fit.
+type BigInteger struct {
+	Value *big.Int
+}
+
+func (i *BigInteger) Inspect() string  { return i.Value.String() }
+func (i *BigInteger) Type() ObjectType { return INTEGER_OBJ }
+
+// NewBigInteger wraps value, returning a plain Integer if it fits in int64.
+func NewBigInteger(value *big.Int) Object {
+	if value.IsInt64() {
+		return &Integer{Value: value.Int64()}
+	}
+	return &BigInteger{Value: value}
+}
+
+type Float struct {
+	Value float64
+}
+
+// Inspect always keeps a decimal point or exponent so floats never read as integers.
+func (f *Float) Inspect() string {
+	str := strconv.FormatFloat(f.Value, 'g', -1, 64)
+	if !strings.ContainsAny(str, ".eEnN") {
+		str += ".0"
+	}
+	return str
+}
+func (f *Float) Type() ObjectType { return FLOAT_OBJ }
+
 type Boolean struct {
 	Value bool
 }
@@ -64,20 +116,124 @@ func (rv *ReturnValue) Type() ObjectType { return RETURN_VALUE_OBJ }
 func (rv *ReturnValue) Inspect() string  { return rv.Value.Inspect() }
 
 type Error struct {
-	Message string
+	Message  string
+	Kind     ErrorKind
+	Position token.Position
+	Trace    []Frame
+}
+
+// ErrorKind tells apart the errors tools such as the type checker need to
+// recognise, whatever their messages say.
+type ErrorKind int
+
+const (
+	OtherError ErrorKind = iota
+	// TypeError is an operation the types of its operands don't support,
+	// such as adding a STRING to an INTEGER or indexing a BOOLEAN.
+	TypeError
+)
+
+// Frame is one Sepia function call that was active when an error was raised.
+type Frame struct {
+	Function string
+	CallSite token.Position
+}
+
+func (f Frame) String() string {
+	if f.CallSite.IsValid() {
+		return "in " + f.Function + ", called at " + f.CallSite.String()
+	}
+	return "in " + f.Function
+}
+
+// maxTraceFrames bounds how many frames StackTrace prints, so that runaway
+// recursion doesn't bury the error itself.
+const maxTraceFrames = 20
+
+// StackTrace renders the call stack, innermost call first, one frame per line.
+func (e *Error) StackTrace() string {
+	var out bytes.Buffer
+
+	for i := len(e.Trace) - 1; i >= 0; i-- {
+		depth := len(e.Trace) - 1 - i
+		if len(e.Trace) > maxTraceFrames && depth == maxTraceFrames/2 {
+			skipped := len(e.Trace) - maxTraceFrames
+			out.WriteString(fmt.Sprintf("    ... %d more frames ...\n", skipped))
+			i -= skipped - 1
+			continue
+		}
+
+		out.WriteString("    " + e.Trace[i].String() + "\n")
+	}
+
+	return out.String()
 }
 
 func (e *Error) Type() ObjectType { return ERROR_OBJ }
-func (e *Error) Inspect() string  { return "ERROR: " + e.Message }
+func (e *Error) Inspect() string {
+	if e.Position.IsValid() {
+		return "ERROR: " + e.Position.String() + ": " + e.Message
+	}
+	return "ERROR: " + e.Message
+}
+
+// Exit is produced by the `exit` builtin and unwinds the whole program, carrying
+// the status code the host should exit with.
+type Exit struct {
+	Code int
+}
+
+func (e *Exit) Type() ObjectType { return EXIT_OBJ }
+func (e *Exit) Inspect() string  { return fmt.Sprintf("exit(%d)", e.Code) }
+
+// HasType reports whether obj may be bound to a name annotated with typeName:
+// a builtin type such as INTEGER, or the name of a structure. FUNCTION also
+// accepts builtin functions.
+func HasType(obj Object, typeName string) bool {
+	if typeName == FUNCTION_OBJ && obj.Type() == BUILTIN_OBJ {
+		return true
+	}
+	return string(obj.Type()) == typeName
+}
+
+// CheckType returns an error if obj does not have typeName, describing what
+// it was being bound to, such as "parameter `n` of `f`"; otherwise nil.
+func CheckType(what string, typeName string, obj Object) *Error {
+	if typeName == "" || HasType(obj, typeName) {
+		return nil
+	}
+	return &Error{Message: fmt.Sprintf("%s must be %s, got %s", what, typeName, obj.Type())}
+}
+
+// ArityError returns an error for a call of the function name with got
+// arguments, when it takes between min and max (-1 for no limit).
+func ArityError(name string, min, max, got int) *Error {
+	var expected string
+	switch {
+	case max < 0:
+		expected = "at least " + util.Plural(min, "argument")
+	case min == max:
+		expected = util.Plural(min, "argument")
+	case max == min+1:
+		expected = fmt.Sprintf("%d or %s", min, util.Plural(max, "argument"))
+	default:
+		expected = fmt.Sprintf("%d to %s", min, util.Plural(max, "argument"))
+	}
+	return &Error{Message: fmt.Sprintf("`%s` expects %s, got %d", name, expected, got)}
+}
 
 type Machine struct {
-	store map[string]Object
-	outer *Machine
+	store     map[string]Object
+	constants map[string]bool
+	types     map[string]string
+	outer     *Machine
 }
 
 func NewMachine() *Machine {
 	s := make(map[string]Object)
-	return &Machine{store: s, outer: nil}
+	c := make(map[string]bool)
+	t := make(map[string]string)
+	return &Machine{store: s, constants: c, types: t, outer: nil}
 }
 
 func NewLocalMachine(outer *Machine) *Machine {
@@ -86,6 +242,27 @@ func NewLocalMachine(outer *Machine) *Machine {
 	return env
 }
 
+// GetLocal looks name up in this scope only, ignoring any enclosing scopes.
+func (e *Machine) GetLocal(name string) (Object, bool) {
+	obj, ok := e.store[name]
+	return obj, ok
+}
+
+// Names lists the bindings made in this scope, sorted.
+func (e *Machine) Names() []string {
+	names := make([]string, 0, len(e.store))
+	for name := range e.store {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsConstant reports whether name is bound as a constant in this scope.
+func (e *Machine) IsConstant(name string) bool {
+	return e.constants[name]
+}
+
 func (e *Machine) Get(name string) (Object, bool) {
 	obj, ok := e.store[name]
 	if !ok && e.outer != nil {
@@ -95,20 +272,49 @@ func (e *Machine) Get(name string) (Object, bool) {
 	return obj, ok
 }
 
+// Set binds name in this scope, refusing to redefine a constant bound in the same scope.
 func (e *Machine) Set(name string, val Object) Object {
+	if e.constants[name] {
+		return &Error{Message: "Cannot redefine constant `" + name + "`."}
+	}
 
 	e.store[name] = val
 	return val
 
 }
 
+// SetConstant binds name in this scope and marks it immutable.
+func (e *Machine) SetConstant(name string, val Object) Object {
+	if result := e.Set(name, val); result != val {
+		return result
+	}
+
+	e.constants[name] = true
+	return val
+}
+
+// SetType records the type annotation of name, bound in this scope, so that
+// updating it to a value of another type is an error. An empty typeName
+// removes the annotation.
+func (e *Machine) SetType(name string, typeName string) {
+	if typeName == "" {
+		delete(e.types, name)
+		return
+	}
+	e.types[name] = typeName
+}
+
 func (e *Machine) Update(name string, val Object) Object {
 	_, ok := e.store[name]
 
 	if !ok && e.outer != nil {
-		e.outer.Update(name, val)
+		return e.outer.Update(name, val)
 	} else if !ok && e.outer == nil {
 		return &Error{Message: "Could not find identitier `" + name + "` in program."}
+	} else if e.constants[name] {
+		return &Error{Message: "Cannot update constant `" + name + "`."}
+	} else if err := CheckType("`"+name+"`", e.types[name], val); err != nil {
+		return err
 	} else {
 		e.store[name] = val
 	}
@@ -118,28 +324,52 @@ func (e *Machine) Update(name string, val Object) Object {
 }
 
 type Function struct {
+	Name       string
 	Parameters []*ast.Identifier
+	Defaults   []ast.Expression
+	Variadic   bool
 	Body       *ast.BlockStatement
 	Machine    *Machine
 }
 
+// Arity returns how many arguments f needs and how many it can take, or -1
+// for the latter if it has a `...rest` parameter.
+func (f *Function) Arity() (int, int) {
+	min := 0
+	for i := range f.Parameters {
+		if i < len(f.Defaults) && f.Defaults[i] != nil || f.Variadic && i == len(f.Parameters)-1 {
+			break
+		}
+		min++
+	}
+	if f.Variadic {
+		return min, -1
+	}
+	return min, len(f.Parameters)
+}
+
 func (f *Function) Type() ObjectType { return FUNCTION_OBJ }
 func (f *Function) Inspect() string {
 	var out bytes.Buffer
 
-	params := []string{}
-	for _, p := range f.Parameters {
-		params = append(params, p.String())
-	}
 	out.WriteString("fn")
 	out.WriteString("(")
-	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(ast.FormatParameters(f.Parameters, f.Defaults, f.Variadic))
 	out.WriteString(") {\n")
 	out.WriteString(f.Body.String())
 	out.WriteString("\n}")
 	return out.String()
 }
 
+// Module is an imported Sepia file; its top-level bindings live in Machine.
+type Module struct {
+	Path    string
+	Machine *Machine
+}
+
+func (m *Module) Type() ObjectType { return MODULE_OBJ }
+func (m *Module) Inspect() string  { return "module(\"" + m.Path + "\")" }
+
 type String struct {
 	Value string
 }
@@ -189,6 +419,26 @@ func (i *Integer) MapKey() MapKey {
 	return MapKey{Type: i.Type(), Value: uint(i.Value)}
 }
 
+func (i *BigInteger) MapKey() MapKey {
+	h := fnv.New64a()
+	h.Write([]byte{byte(i.Value.Sign() + 1)})
+	h.Write(i.Value.Bytes())
+	return MapKey{Type: i.Type(), Value: uint(h.Sum64())}
+}
+
+// MapKey hashes -0.0 like 0.0, since the two are equal. Every NaN hashes
+// alike too, so a NaN key can be looked up again although NaN == NaN is false.
+func (f *Float) MapKey() MapKey {
+	value := f.Value
+	switch {
+	case value == 0:
+		value = 0
+	case math.IsNaN(value):
+		value = math.NaN()
+	}
+	return MapKey{Type: f.Type(), Value: uint(math.Float64bits(value))}
+}
+
 func (s *String) MapKey() MapKey {
 	h := fnv.New64a()
 	_, ok := h.Write([]byte(s.Value))
@@ -198,6 +448,43 @@ func (s *String) MapKey() MapKey {
 	return MapKey{Type: s.Type(), Value: uint(h.Sum64())}
 }
 
+// Structure is a declared structure. Calling it with a value for each field,
+// in order, makes a Struct.
+type Structure struct {
+	Name   string
+	Fields []string
+}
+
+func (s *Structure) Type() ObjectType { return STRUCTURE_OBJ }
+func (s *Structure) Inspect() string {
+	return "structure " + s.Name + " -> " + strings.Join(s.Fields, ", ") + " end"
+}
+
+// Struct is an instance of a Structure. Its type is the structure's name.
+type Struct struct {
+	Structure *Structure
+	Values    []Object // in the order of Structure.Fields
+}
+
+func (s *Struct) Type() ObjectType { return ObjectType(s.Structure.Name) }
+func (s *Struct) Inspect() string {
+	fields := []string{}
+	for i, field := range s.Structure.Fields {
+		fields = append(fields, field+": "+s.Values[i].Inspect())
+	}
+	return s.Structure.Name + " { " + strings.Join(fields, ", ") + " }"
+}
+
+// Get looks up the value of a field.
+func (s *Struct) Get(field string) (Object, bool) {
+	for i, name := range s.Structure.Fields {
+		if name == field {
+			return s.Values[i], true
+		}
+	}
+	return nil, false
+}
+
 type MapPair struct {
 	Key   Object
 	Value Object
@@ -6,6 +6,7 @@ import "sepia/token"
 const (
 	_ int = iota
 	LOWEST
+	ASSIGN // x += y
 	AND
 	OR
 	EQUALS      // ==
@@ -13,12 +14,17 @@ const (
 
 	SUM     //+
 	PRODUCT //*
+	POWER   // ** (right associative)
 	PREFIX  //-Xor!X
 	CALL    // myFunction(X)
 	INDEX
 )
 
 var precedences = map[token.Type]int{
+	token.PLUSEQ:   ASSIGN,
+	token.MINUSEQ:  ASSIGN,
+	token.MULEQ:    ASSIGN,
+	token.SLASHEQ:  ASSIGN,
 	token.EQ:       EQUALS,
 	token.NOT_EQ:   EQUALS,
 	token.LT:       LESSGREATER,
@@ -31,6 +37,10 @@ var precedences = map[token.Type]int{
 	token.MINUS:    SUM,
 	token.ASTERISK: PRODUCT,
 	token.SLASH:    PRODUCT,
+	token.FLOORDIV: PRODUCT,
+	token.PERCENT:  PRODUCT,
+	token.POWER:    POWER,
 	token.LPAREN:   CALL,
 	token.LBRACKET: INDEX,
+	token.DOT:      INDEX,
 }
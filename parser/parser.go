@@ -1,11 +1,14 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
+	"math/big"
 	"sepia/ast"
 	"sepia/lexer"
 	"sepia/token"
 	"strconv"
+	"strings"
 )
 
 type Parser struct {
@@ -30,29 +33,36 @@ func New(l *lexer.Lexer) *Parser {
 	p.prefixParseFns = make(map[token.Type]prefixParseFn)
 	p.registerPrefixFunction(token.IDENT, p.parseIdentifier)
 	p.registerPrefixFunction(token.INT, p.parseIntegerLiteral)
+	p.registerPrefixFunction(token.FLOAT, p.parseFloatLiteral)
 	p.registerPrefixFunction(token.LBRACKET, p.parseArrayLiteral)
+	p.registerPrefixFunction(token.TEMPLATE_HEAD, p.parseTemplateLiteral)
 	p.registerPrefixFunction(token.BANG, p.parsePrefixExpression)
-	p.registerPrefixFunction(token.INCREMENT, p.parsePrefixExpression)
-	p.registerPrefixFunction(token.DECREMENT, p.parsePrefixExpression)
+	p.registerPrefixFunction(token.INCREMENT, p.parseIncrementExpression)
+	p.registerPrefixFunction(token.DECREMENT, p.parseIncrementExpression)
 	p.registerPrefixFunction(token.MINUS, p.parsePrefixExpression)
 	p.registerPrefixFunction(token.TRUE, p.parseBoolean)
 	p.registerPrefixFunction(token.FALSE, p.parseBoolean)
 	p.registerPrefixFunction(token.FALSE, p.parseBoolean)
 	p.registerPrefixFunction(token.LPAREN, p.parseGroupedExpression)
 	p.registerPrefixFunction(token.IF, p.parseIfExpression)
+	p.registerPrefixFunction(token.MATCH, p.parseMatchExpression)
 	p.registerPrefixFunction(token.FUNCTION, p.parseFunctionLiteral)
 	p.registerPrefixFunction(token.STRING, p.parseString)
 	p.registerPrefixFunction(token.LBRACE, p.parseMapLiteral)
+	p.registerPrefixFunction(token.IMPORT, p.parseImportExpression)
 
 	p.infixParseFns = make(map[token.Type]infixParseFn)
 	p.registerInfixFunction(token.PLUS, p.parseInfixExpression)
 	p.registerInfixFunction(token.MINUS, p.parseInfixExpression)
 	p.registerInfixFunction(token.SLASH, p.parseInfixExpression)
 	p.registerInfixFunction(token.ASTERISK, p.parseInfixExpression)
-	p.registerInfixFunction(token.MINUSEQ, p.parseInfixExpression)
-	p.registerInfixFunction(token.PLUSEQ, p.parseInfixExpression)
-	p.registerInfixFunction(token.MULEQ, p.parseInfixExpression)
-	p.registerInfixFunction(token.SLASHEQ, p.parseInfixExpression)
+	p.registerInfixFunction(token.FLOORDIV, p.parseInfixExpression)
+	p.registerInfixFunction(token.PERCENT, p.parseInfixExpression)
+	p.registerInfixFunction(token.POWER, p.parseInfixExpression)
+	p.registerInfixFunction(token.MINUSEQ, p.parseAssignmentExpression)
+	p.registerInfixFunction(token.PLUSEQ, p.parseAssignmentExpression)
+	p.registerInfixFunction(token.MULEQ, p.parseAssignmentExpression)
+	p.registerInfixFunction(token.SLASHEQ, p.parseAssignmentExpression)
 	p.registerInfixFunction(token.EQ, p.parseInfixExpression)
 	p.registerInfixFunction(token.NOT_EQ, p.parseInfixExpression)
 	p.registerInfixFunction(token.LT, p.parseInfixExpression)
@@ -63,6 +73,7 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfixFunction(token.GTEQ, p.parseInfixExpression)
 	p.registerInfixFunction(token.LPAREN, p.parseCallExpression)
 	p.registerInfixFunction(token.LBRACKET, p.parseIndexExpression)
+	p.registerInfixFunction(token.DOT, p.parseMemberExpression)
 
 	return p
 }
@@ -141,11 +152,19 @@ func (p *Parser) ParseProgram() *ast.Program {
 // UTILITY/ERRORS
 //
 
-func (p *Parser) addPeekError(tok token.Type) {
-	msg := fmt.Sprintf("Expected next token to be %s, got %s instead", tok, p.peekToken)
+// addError records a parse error prefixed with the source position it refers to.
+func (p *Parser) addError(position token.Position, format string, a ...interface{}) {
+	msg := fmt.Sprintf(format, a...)
+	if position.IsValid() {
+		msg = position.String() + ": " + msg
+	}
 	p.errors = append(p.errors, msg)
 }
 
+func (p *Parser) addPeekError(tok token.Type) {
+	p.addError(p.peekToken.Position, "Expected next token to be %s, got %s (%q) instead", tok, p.peekToken.Type, p.peekToken.Literal)
+}
+
 //
 // UTILITY/REGISTRATION
 //
@@ -189,9 +208,15 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 
 	value, err := strconv.ParseInt(p.currentToken.Literal, 0, 64)
 
+	if errors.Is(err, strconv.ErrRange) {
+		if bigValue, ok := new(big.Int).SetString(p.currentToken.Literal, 0); ok {
+			literal.Big = bigValue
+			return literal
+		}
+	}
+
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as integer", p.currentToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.addError(p.currentToken.Position, "could not parse %q as integer", p.currentToken.Literal)
 		return nil
 
 	}
@@ -200,41 +225,106 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 	return literal
 }
 
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	defer untrace(trace("parseFloatLiteral"))
+	literal := &ast.FloatLiteral{Token: p.currentToken}
+
+	value, err := strconv.ParseFloat(p.currentToken.Literal, 64)
+
+	if err != nil {
+		p.addError(p.currentToken.Position, "could not parse %q as float", p.currentToken.Literal)
+		return nil
+	}
+
+	literal.Value = value
+	return literal
+}
+
 func (p *Parser) parseString() ast.Expression {
 	return &ast.StringLiteral{Token: p.currentToken, Value: p.currentToken.Literal}
 }
 
-func (p *Parser) parseFunctionParameters() []*ast.Identifier {
-	identifiers := []*ast.Identifier{}
+// parseFunctionParameters parses the parameters of fnLit, each of which may
+// have a type annotation and a default value (`n: INTEGER = 1`). Parameters
+// with defaults must come after those without, and the last parameter may be
+// a `...rest` parameter collecting any further arguments.
+func (p *Parser) parseFunctionParameters(fnLit *ast.FunctionLiteral) {
+	fnLit.Parameters = []*ast.Identifier{}
 
 	if p.peekTokenIs(token.RPAREN) {
 		p.consumeToken()
-		return identifiers
+		return
 	}
 
-	p.consumeToken()
+	for {
+		if fnLit.Variadic {
+			p.addError(p.peekToken.Position, "`...%s` must be the last parameter", fnLit.Parameters[len(fnLit.Parameters)-1].Value)
+		}
 
-	ident := &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
-	identifiers = append(identifiers, ident)
+		if p.peekTokenIs(token.ELLIPSIS) {
+			p.consumeToken()
+			fnLit.Variadic = true
+		}
 
-	for p.peekTokenIs(token.COMMA) {
-		p.consumeToken()
-		p.consumeToken()
+		if !p.expectPeek(token.IDENT) {
+			return
+		}
 		ident := &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
-		identifiers = append(identifiers, ident)
+		if !p.parseTypeAnnotation(ident) {
+			return
+		}
+
+		var value ast.Expression
+		if p.peekTokenIs(token.ASSIGN) {
+			if fnLit.Variadic {
+				p.addError(p.peekToken.Position, "`...%s` cannot have a default value", ident.Value)
+			}
+			p.consumeToken()
+			p.consumeToken()
+			value = p.parseExpression(LOWEST)
+		} else if !fnLit.Variadic && len(fnLit.Defaults) > 0 && fnLit.Defaults[len(fnLit.Defaults)-1] != nil {
+			p.addError(ident.Pos(), "parameter `%s` needs a default value, like the parameters before it", ident.Value)
+		}
 
+		fnLit.Parameters = append(fnLit.Parameters, ident)
+		fnLit.Defaults = append(fnLit.Defaults, value)
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.consumeToken()
 	}
 
-	if !p.expectPeek(token.RPAREN) {
-		return nil
+	p.expectPeek(token.RPAREN)
+}
+
+// parseTypeAnnotation parses the optional `: TYPE` after a parameter or the
+// name in a binding, reporting false if it is malformed.
+func (p *Parser) parseTypeAnnotation(ident *ast.Identifier) bool {
+	if !p.peekTokenIs(token.COLON) {
+		return true
 	}
+	p.consumeToken()
 
-	return identifiers
+	if !p.expectPeek(token.IDENT) {
+		return false
+	}
+	ident.Type = &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+	return true
 }
 
 func (p *Parser) parseIdentifier() ast.Expression {
 	defer untrace(trace("parseIdentifier"))
-	return &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+	identifier := &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+
+	// `x++` on one line is a postfix increment, which Sepia doesn't have.
+	if (p.peekTokenIs(token.INCREMENT) || p.peekTokenIs(token.DECREMENT)) &&
+		p.peekToken.Position.Line == p.currentToken.Position.Line {
+		p.consumeToken()
+		p.addError(p.currentToken.Position, "postfix %s is not supported; use %s%s", p.currentToken.Literal, p.currentToken.Literal, identifier.Value)
+	}
+
+	return identifier
 }
 
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
@@ -306,6 +396,10 @@ func (p *Parser) parseStatement() ast.Statement {
 		return p.parseLetStatement()
 	case token.UPDATE:
 		return p.parseUpdateStatement()
+	case token.CONSTANT:
+		return p.parseConstantStatement()
+	case token.STRUCTURE:
+		return p.parseStructureStatement()
 	case token.RETURN:
 		return p.parseReturnStatement()
 	default:
@@ -334,7 +428,7 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 
 	stmt.ReturnValue = p.parseExpression(LOWEST)
 
-	for !p.currentTokenIs(token.SEMICOLON) {
+	if p.peekTokenIs(token.SEMICOLON) {
 		p.consumeToken()
 	}
 
@@ -348,6 +442,9 @@ func (p *Parser) parseLetStatement() *ast.ValueStatement {
 		return nil
 	}
 	stmt.Name = &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+	if !p.parseTypeAnnotation(stmt.Name) {
+		return nil
+	}
 
 	if !p.expectPeek(token.ASSIGN) {
 		return nil
@@ -379,6 +476,64 @@ func (p *Parser) parseUpdateStatement() *ast.UpdateStatement {
 	return stmt
 }
 
+func (p *Parser) parseConstantStatement() *ast.ConstantStatement {
+	defer untrace(trace("parseConstantStatement"))
+	stmt := &ast.ConstantStatement{Token: p.currentToken}
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	stmt.Name = &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+	if !p.parseTypeAnnotation(stmt.Name) {
+		return nil
+	}
+
+	if !p.expectPeek(token.ASSIGN) {
+		return nil
+	}
+	p.consumeToken()
+	stmt.Value = p.parseExpression(LOWEST)
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.consumeToken()
+	}
+	return stmt
+}
+
+// parseStructureStatement parses `structure Name -> field, field end`. The
+// commas between fields are optional, so fields can also go one per line.
+func (p *Parser) parseStructureStatement() *ast.StructureStatement {
+	defer untrace(trace("parseStructureStatement"))
+	stmt := &ast.StructureStatement{Token: p.currentToken}
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	stmt.Name = &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+
+	if !p.expectPeek(token.OPENBLOCK) {
+		return nil
+	}
+
+	seen := map[string]bool{}
+	for !p.peekTokenIs(token.CLOSEBLOCK) {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+
+		field := &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+		if seen[field.Value] {
+			p.addError(field.Pos(), "duplicate field `%s` in structure `%s`", field.Value, stmt.Name.Value)
+		}
+		seen[field.Value] = true
+		stmt.Fields = append(stmt.Fields, field)
+
+		if p.peekTokenIs(token.COMMA) {
+			p.consumeToken()
+		}
+	}
+	p.consumeToken()
+
+	return stmt
+}
+
 //
 // PARSING/EXPRESSIONS
 //
@@ -392,6 +547,9 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	}
 
 	precedence := p.currentPrecedence()
+	if p.currentTokenIs(token.POWER) {
+		precedence-- // right associative: 2 ** 3 ** 2 is 2 ** (3 ** 2)
+	}
 	p.consumeToken()
 	expression.Right = p.parseExpression(precedence)
 
@@ -403,6 +561,10 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 	prefix := p.prefixParseFns[p.currentToken.Type]
 
 	if prefix == nil {
+		if p.currentTokenIs(token.ILLEGAL) {
+			p.illegalTokenError()
+			return nil
+		}
 
 		p.noPrefixParseFnError(p.currentToken.Type)
 		return nil
@@ -432,9 +594,97 @@ func (p *Parser) parsePrefixExpression() ast.Expression {
 	return expression
 }
 
+// parseAssignmentExpression parses `x += y` and friends. They are right
+// associative, and only a name can be assigned to.
+func (p *Parser) parseAssignmentExpression(left ast.Expression) ast.Expression {
+	defer untrace(trace("parseAssignmentExpression"))
+	expression := &ast.AssignmentExpression{
+		Token:    p.currentToken,
+		Operator: p.currentToken.Literal,
+	}
+
+	name, ok := left.(*ast.Identifier)
+	if !ok {
+		p.addError(p.currentToken.Position, "cannot assign to %s with %s, only to a name", left, p.currentToken.Literal)
+		return nil
+	}
+	expression.Name = name
+
+	p.consumeToken()
+	expression.Value = p.parseExpression(ASSIGN - 1)
+
+	return expression
+}
+
+func (p *Parser) parseIncrementExpression() ast.Expression {
+	defer untrace(trace("parseIncrementExpression"))
+	expression := &ast.IncrementExpression{
+		Token:    p.currentToken,
+		Operator: p.currentToken.Literal,
+	}
+
+	if !p.peekTokenIs(token.IDENT) {
+		operand := strconv.Quote(p.peekToken.Literal)
+		if p.peekToken.Literal == "" {
+			operand = string(p.peekToken.Type)
+		}
+		p.addError(p.peekToken.Position, "cannot apply %s to %s, only to a name", expression.Operator, operand)
+		return nil
+	}
+	p.consumeToken()
+	expression.Name = &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+
+	return expression
+}
+
 func (p *Parser) noPrefixParseFnError(t token.Type) {
-	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
+	p.addError(p.currentToken.Position, "no prefix parse function for %s found", t)
+}
+
+// parseTemplateLiteral parses an interpolated string from its TEMPLATE_HEAD
+// through the TEMPLATE_TAIL, with an expression before each MIDDLE and the TAIL.
+func (p *Parser) parseTemplateLiteral() ast.Expression {
+	template := &ast.TemplateLiteral{Token: p.currentToken}
+
+	for {
+		if p.currentToken.Literal != "" {
+			template.Parts = append(template.Parts, &ast.StringLiteral{Token: p.currentToken, Value: p.currentToken.Literal})
+		}
+		if p.currentTokenIs(token.TEMPLATE_TAIL) {
+			return template
+		}
+
+		p.consumeToken()
+		if p.currentTokenIs(token.TEMPLATE_MIDDLE) || p.currentTokenIs(token.TEMPLATE_TAIL) {
+			p.addError(p.currentToken.Position, "empty interpolation `{}` in string")
+			return nil
+		}
+		expression := p.parseExpression(LOWEST)
+		if expression == nil {
+			return nil
+		}
+		template.Parts = append(template.Parts, expression)
+
+		if !p.peekTokenIs(token.TEMPLATE_MIDDLE) && !p.peekTokenIs(token.TEMPLATE_TAIL) {
+			p.addError(p.peekToken.Position, "Expected `}` to close the interpolation, got %s (%q) instead", p.peekToken.Type, p.peekToken.Literal)
+			return nil
+		}
+		p.consumeToken()
+	}
+}
+
+// illegalTokenError explains what the lexer could not make sense of.
+func (p *Parser) illegalTokenError() {
+	literal := p.currentToken.Literal
+
+	switch {
+	case strings.HasPrefix(literal, "\"") || strings.HasPrefix(literal, "`"):
+		p.addError(p.currentToken.Position, "unterminated string")
+	case strings.HasPrefix(literal, "\\"):
+		p.addError(p.currentToken.Position, "invalid escape sequence %s in string", literal)
+	default:
+		p.addError(p.currentToken.Position, "illegal character %q", literal)
+	}
 }
 
 func (p *Parser) parseIfExpression() ast.Expression {
@@ -471,6 +721,197 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	return expression
 }
 
+// parseMatchExpression parses a match expression and its arms, each a
+// pattern with an optional `if (guard)` followed by a block.
+func (p *Parser) parseMatchExpression() ast.Expression {
+	expression := &ast.MatchExpression{Token: p.currentToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	p.consumeToken()
+	expression.Subject = p.parseExpression(LOWEST)
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.OPENBLOCK) {
+		return nil
+	}
+
+	for !p.peekTokenIs(token.CLOSEBLOCK) {
+		if p.peekTokenIs(token.EOF) {
+			p.addPeekError(token.CLOSEBLOCK)
+			return nil
+		}
+		p.consumeToken()
+
+		arm := &ast.MatchArm{Pattern: p.parsePattern()}
+		if arm.Pattern == nil {
+			return nil
+		}
+		p.checkPatternBindings(arm.Pattern)
+
+		if p.peekTokenIs(token.IF) {
+			p.consumeToken()
+			if !p.expectPeek(token.LPAREN) {
+				return nil
+			}
+			p.consumeToken()
+			arm.Guard = p.parseExpression(LOWEST)
+			if !p.expectPeek(token.RPAREN) {
+				return nil
+			}
+		}
+
+		if !p.expectPeek(token.OPENBLOCK) {
+			return nil
+		}
+		arm.Body = p.parseBlockStatement()
+		expression.Arms = append(expression.Arms, arm)
+	}
+	p.consumeToken()
+
+	return expression
+}
+
+// parsePattern parses the pattern starting at the current token.
+func (p *Parser) parsePattern() ast.Pattern {
+	switch p.currentToken.Type {
+	case token.IDENT:
+		name := &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+		if p.peekTokenIs(token.LBRACE) {
+			p.consumeToken()
+			return p.parseStructPattern(name)
+		}
+		if !p.parseTypeAnnotation(name) {
+			return nil
+		}
+		return &ast.BindingPattern{Name: name}
+	case token.LBRACKET:
+		return p.parseArrayPattern()
+	case token.LBRACE:
+		return p.parseMapPattern()
+	case token.INT, token.FLOAT, token.STRING, token.TRUE, token.FALSE:
+		return &ast.LiteralPattern{Value: p.parseExpression(PREFIX)}
+	case token.MINUS:
+		if !p.peekTokenIs(token.INT) && !p.peekTokenIs(token.FLOAT) {
+			p.addError(p.peekToken.Position, "expected a number after `-` in pattern, got %s", p.peekToken.Type)
+			return nil
+		}
+		return &ast.LiteralPattern{Value: p.parseExpression(PREFIX)}
+	case token.ILLEGAL:
+		p.illegalTokenError()
+		return nil
+	default:
+		p.addError(p.currentToken.Position, "%s cannot start a pattern", p.currentToken.Type)
+		return nil
+	}
+}
+
+func (p *Parser) parseArrayPattern() ast.Pattern {
+	pattern := &ast.ArrayPattern{Token: p.currentToken}
+
+	for !p.peekTokenIs(token.RBRACKET) {
+		if pattern.Rest != nil {
+			p.addError(p.peekToken.Position, "`...%s` must be the last element of the pattern", pattern.Rest.Value)
+		}
+
+		p.consumeToken()
+		if p.currentTokenIs(token.ELLIPSIS) {
+			if !p.expectPeek(token.IDENT) {
+				return nil
+			}
+			pattern.Rest = &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+		} else {
+			element := p.parsePattern()
+			if element == nil {
+				return nil
+			}
+			pattern.Elements = append(pattern.Elements, element)
+		}
+
+		if !p.peekTokenIs(token.RBRACKET) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	p.consumeToken()
+
+	return pattern
+}
+
+func (p *Parser) parseMapPattern() ast.Pattern {
+	pattern := &ast.MapPattern{Token: p.currentToken}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.consumeToken()
+		switch p.currentToken.Type {
+		case token.STRING, token.INT, token.TRUE, token.FALSE:
+			pattern.Keys = append(pattern.Keys, p.parseExpression(PREFIX))
+		default:
+			p.addError(p.currentToken.Position, "map pattern keys must be strings, integers or booleans, got %s", p.currentToken.Type)
+			return nil
+		}
+
+		if !p.expectPeek(token.COLON) {
+			return nil
+		}
+		p.consumeToken()
+		value := p.parsePattern()
+		if value == nil {
+			return nil
+		}
+		pattern.Values = append(pattern.Values, value)
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	p.consumeToken()
+
+	return pattern
+}
+
+func (p *Parser) parseStructPattern(name *ast.Identifier) ast.Pattern {
+	pattern := &ast.StructPattern{Name: name}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		field := &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+
+		var value ast.Pattern = &ast.BindingPattern{Name: field}
+		if p.peekTokenIs(token.COLON) {
+			p.consumeToken()
+			p.consumeToken()
+			if value = p.parsePattern(); value == nil {
+				return nil
+			}
+		}
+		pattern.Fields = append(pattern.Fields, field)
+		pattern.Values = append(pattern.Values, value)
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	p.consumeToken()
+
+	return pattern
+}
+
+// checkPatternBindings reports names bound more than once by one pattern.
+func (p *Parser) checkPatternBindings(pattern ast.Pattern) {
+	seen := map[string]bool{}
+	for _, name := range ast.PatternBindings(pattern) {
+		if seen[name.Value] {
+			p.addError(name.Pos(), "`%s` is bound more than once in one pattern", name.Value)
+		}
+		seen[name.Value] = true
+	}
+}
+
 func (p *Parser) parseFunctionLiteral() ast.Expression {
 	fnLit := &ast.FunctionLiteral{Token: p.currentToken}
 
@@ -478,7 +919,7 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 		return nil
 	}
 
-	fnLit.Parameters = p.parseFunctionParameters()
+	p.parseFunctionParameters(fnLit)
 
 	if !p.expectPeek(token.OPENBLOCK) {
 		return nil
@@ -532,3 +973,27 @@ func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 
 	return indexExp
 }
+
+func (p *Parser) parseMemberExpression(left ast.Expression) ast.Expression {
+	memberExp := &ast.MemberExpression{Object: left, Token: p.currentToken}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	memberExp.Property = &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+
+	return memberExp
+}
+
+func (p *Parser) parseImportExpression() ast.Expression {
+	importExp := &ast.ImportExpression{Token: p.currentToken}
+
+	if !p.expectPeek(token.STRING) {
+		return nil
+	}
+
+	importExp.Path = p.currentToken.Literal
+
+	return importExp
+}
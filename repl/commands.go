@@ -0,0 +1,141 @@
+package repl
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sepia/lexer"
+	"sepia/objects"
+	"sepia/token"
+	"strings"
+)
+
+type command struct {
+	name  string
+	usage string
+	help  string
+	// run carries out the command, returning false to end the session.
+	run func(s *session, arg string) bool
+}
+
+var commands []*command
+
+func init() {
+	commands = []*command{
+		{"env", ":env", "list the bindings in this session", (*session).env},
+		{"type", ":type <expr>", "evaluate an expression and show its type", (*session).typeOf},
+		{"ast", ":ast <expr>", "show how an expression parses", (*session).ast},
+		{"tokens", ":tokens <expr>", "show the tokens an expression lexes into", (*session).tokens},
+		{"load", ":load <file.sp>", "evaluate a file into this session, importing its imports afresh", (*session).load},
+		{"reset", ":reset", "forget every binding and imported module in this session", (*session).reset},
+		{"help", ":help", "list the meta-commands", (*session).help},
+		{"quit", ":quit", "leave the REPL", func(*session, string) bool { return false }},
+	}
+}
+
+// runCommand runs a `:name arg` meta-command, returning false to end the session.
+func (s *session) runCommand(input string) bool {
+	name, arg := input[1:], ""
+	if idx := strings.IndexAny(name, " \t"); idx >= 0 {
+		name, arg = name[:idx], strings.TrimSpace(name[idx:])
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd.run(s, arg)
+		}
+	}
+
+	fmt.Fprintf(s.out, "❌ unknown command :%s, try :help\n", name)
+	return true
+}
+
+func (s *session) env(string) bool {
+	names := s.machine.Names()
+	if len(names) == 0 {
+		fmt.Fprintln(s.out, "no bindings yet")
+	}
+
+	for _, name := range names {
+		val, _ := s.machine.Get(name)
+		keyword := "value"
+		if s.machine.IsConstant(name) {
+			keyword = "constant"
+		}
+		// Only the first line of multi-line values (function bodies) is shown.
+		inspected := strings.SplitN(val.Inspect(), "\n", 2)
+		summary := inspected[0]
+		if len(inspected) > 1 {
+			summary += " … }"
+		}
+		fmt.Fprintf(s.out, "%s %s: %s = %s\n", keyword, name, val.Type(), summary)
+	}
+
+	return true
+}
+
+func (s *session) typeOf(arg string) bool {
+	program, ok := s.parse(arg, "")
+	if !ok {
+		return true
+	}
+
+	evaluated := s.interpreter.Run(program, s.machine)
+	if evaluated == nil {
+		fmt.Fprintln(s.out, "statements have no type")
+	} else if runtimeErr, ok := evaluated.(*objects.Error); ok {
+		fmt.Fprintln(s.out, runtimeErr.Inspect())
+	} else {
+		fmt.Fprintln(s.out, evaluated.Type())
+	}
+
+	return true
+}
+
+func (s *session) ast(arg string) bool {
+	program, ok := s.parse(arg, "")
+	if !ok {
+		return true
+	}
+
+	for _, statement := range program.Statements {
+		fmt.Fprintf(s.out, "%T %s\n", statement, statement.String())
+	}
+
+	return true
+}
+
+func (s *session) tokens(arg string) bool {
+	l := lexer.New(arg)
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		fmt.Fprintf(s.out, "%s\t%-12s %q\n", tok.Position, tok.Type, tok.Literal)
+	}
+
+	return true
+}
+
+func (s *session) load(arg string) bool {
+	data, err := ioutil.ReadFile(arg)
+	if err != nil {
+		fmt.Fprintf(s.out, "❌ %s\n", err)
+		return true
+	}
+
+	s.interpreter.ResetModules()
+	s.eval(string(data), arg)
+	return true
+}
+
+func (s *session) reset(string) bool {
+	s.machine = objects.NewMachine()
+	s.interpreter.ResetModules()
+	fmt.Fprintln(s.out, "session reset")
+	return true
+}
+
+func (s *session) help(string) bool {
+	for _, cmd := range commands {
+		fmt.Fprintf(s.out, "%-16s %s\n", cmd.usage, cmd.help)
+	}
+
+	return true
+}
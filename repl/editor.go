@@ -0,0 +1,378 @@
+package repl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// lineReader reads one line of input after showing prompt.
+type lineReader interface {
+	ReadLine(prompt string) (string, error)
+}
+
+// errInterrupted is returned when the user abandons a line with Ctrl-C.
+var errInterrupted = errors.New("interrupted")
+
+// scannerReader reads lines from a non-interactive input, such as a pipe.
+type scannerReader struct {
+	scanner *bufio.Scanner
+	out     io.Writer
+}
+
+func (r *scannerReader) ReadLine(prompt string) (string, error) {
+	fmt.Fprint(r.out, prompt)
+	if !r.scanner.Scan() {
+		return "", io.EOF
+	}
+
+	return r.scanner.Text(), nil
+}
+
+const (
+	keyCtrlA     = 1
+	keyCtrlB     = 2
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlF     = 6
+	keyCtrlG     = 7
+	keyCtrlH     = 8
+	keyTab       = 9
+	keyCtrlK     = 11
+	keyCtrlL     = 12
+	keyEnter     = 13
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlR     = 18
+	keyCtrlU     = 21
+	keyCtrlW     = 23
+	keyEscape    = 27
+	keyBackspace = 127
+)
+
+// editor is an emacs-style line editor for interactive terminals, with
+// history navigation, reverse search and tab completion.
+type editor struct {
+	in       *bufio.Reader
+	out      io.Writer
+	terminal *terminal
+	history  *history
+	// completions lists every word that could be completed at the cursor.
+	completions func() []string
+}
+
+// lineState is the line being edited.
+type lineState struct {
+	prompt     string
+	buf        []rune
+	pos        int
+	historyIdx int
+	saved      []rune // the new line, kept while browsing history
+}
+
+func (e *editor) ReadLine(prompt string) (string, error) {
+	if err := e.terminal.makeRaw(); err != nil {
+		return "", err
+	}
+	defer func() { _ = e.terminal.restore() }()
+
+	st := &lineState{prompt: prompt, historyIdx: len(e.history.entries)}
+	e.refresh(st)
+
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return "", err
+		}
+
+		if r == keyCtrlR {
+			var submit bool
+			if r, submit = e.reverseSearch(st); submit {
+				r = keyEnter
+			}
+		}
+
+		switch r {
+		case keyEnter, '\n':
+			fmt.Fprint(e.out, "\r\n")
+			line := string(st.buf)
+			e.history.add(line)
+			return line, nil
+		case keyCtrlC:
+			fmt.Fprint(e.out, "^C\r\n")
+			return "", errInterrupted
+		case keyCtrlD:
+			if len(st.buf) == 0 {
+				fmt.Fprint(e.out, "\r\n")
+				return "", io.EOF
+			}
+			st.deleteAt(st.pos)
+		case keyCtrlA:
+			st.pos = 0
+		case keyCtrlE:
+			st.pos = len(st.buf)
+		case keyCtrlB:
+			st.move(-1)
+		case keyCtrlF:
+			st.move(1)
+		case keyCtrlK:
+			st.buf = st.buf[:st.pos]
+		case keyCtrlU:
+			st.buf = append([]rune{}, st.buf[st.pos:]...)
+			st.pos = 0
+		case keyCtrlW:
+			start := st.pos
+			for start > 0 && unicode.IsSpace(st.buf[start-1]) {
+				start--
+			}
+			for start > 0 && !unicode.IsSpace(st.buf[start-1]) {
+				start--
+			}
+			st.buf = append(st.buf[:start], st.buf[st.pos:]...)
+			st.pos = start
+		case keyCtrlL:
+			fmt.Fprint(e.out, "\x1b[H\x1b[2J")
+		case keyCtrlP:
+			e.historyMove(st, -1)
+		case keyCtrlN:
+			e.historyMove(st, 1)
+		case keyTab:
+			e.complete(st)
+		case keyBackspace, keyCtrlH:
+			if st.pos > 0 {
+				st.pos--
+				st.deleteAt(st.pos)
+			}
+		case keyEscape:
+			e.escapeSequence(st)
+		case 0:
+			// a reverse search that was cancelled
+		default:
+			if unicode.IsPrint(r) {
+				st.buf = append(st.buf[:st.pos], append([]rune{r}, st.buf[st.pos:]...)...)
+				st.pos++
+			}
+		}
+
+		e.refresh(st)
+	}
+}
+
+// refresh redraws the prompt and line, and puts the cursor back in place.
+func (e *editor) refresh(st *lineState) {
+	fmt.Fprintf(e.out, "\r%s%s\x1b[K", st.prompt, string(st.buf))
+	if back := len(st.buf) - st.pos; back > 0 {
+		fmt.Fprintf(e.out, "\x1b[%dD", back)
+	}
+}
+
+func (st *lineState) move(delta int) {
+	st.pos += delta
+	if st.pos < 0 {
+		st.pos = 0
+	}
+	if st.pos > len(st.buf) {
+		st.pos = len(st.buf)
+	}
+}
+
+func (st *lineState) deleteAt(pos int) {
+	if pos < len(st.buf) {
+		st.buf = append(st.buf[:pos], st.buf[pos+1:]...)
+	}
+}
+
+func (st *lineState) setLine(line []rune) {
+	st.buf = append([]rune{}, line...)
+	st.pos = len(st.buf)
+}
+
+// historyMove steps through history; delta is -1 for older and 1 for newer.
+func (e *editor) historyMove(st *lineState, delta int) {
+	idx := st.historyIdx + delta
+	if idx < 0 || idx > len(e.history.entries) {
+		return
+	}
+
+	if st.historyIdx == len(e.history.entries) {
+		st.saved = append([]rune{}, st.buf...)
+	}
+
+	st.historyIdx = idx
+	if idx == len(e.history.entries) {
+		st.setLine(st.saved)
+	} else {
+		st.setLine([]rune(e.history.entries[idx]))
+	}
+}
+
+// escapeSequence handles the arrow, home, end and delete keys.
+func (e *editor) escapeSequence(st *lineState) {
+	r, _, err := e.in.ReadRune()
+	if err != nil || (r != '[' && r != 'O') {
+		return
+	}
+
+	r, _, err = e.in.ReadRune()
+	if err != nil {
+		return
+	}
+
+	// Sequences with parameters, like ESC [ 3 ~ or ESC [ 1 ; 5 C, run until a
+	// final byte in '@'...'~'. The first parameter picks the key of a '~'
+	// sequence; the others, such as modifiers, are ignored.
+	var params []string
+	if unicode.IsDigit(r) || r == ';' {
+		param := []rune{}
+		for ; r < '@' || r > '~'; r, _, err = e.in.ReadRune() {
+			if err != nil {
+				return
+			}
+			if r == ';' {
+				params = append(params, string(param))
+				param = param[:0]
+			} else {
+				param = append(param, r)
+			}
+		}
+		params = append(params, string(param))
+	}
+
+	switch r {
+	case 'A':
+		e.historyMove(st, -1)
+	case 'B':
+		e.historyMove(st, 1)
+	case 'C':
+		st.move(1)
+	case 'D':
+		st.move(-1)
+	case 'H':
+		st.pos = 0
+	case 'F':
+		st.pos = len(st.buf)
+	case '~':
+		if len(params) == 0 {
+			return
+		}
+		switch params[0] {
+		case "1", "7":
+			st.pos = 0
+		case "4", "8":
+			st.pos = len(st.buf)
+		case "3":
+			st.deleteAt(st.pos)
+		}
+	}
+}
+
+// reverseSearch runs an incremental search backwards through history (Ctrl-R).
+// It returns the key that ended the search, to be handled as usual once the
+// match is in the buffer, and whether that key submits the line.
+func (e *editor) reverseSearch(st *lineState) (rune, bool) {
+	var query []rune
+	matchIdx := len(e.history.entries)
+	match := string(st.buf)
+
+	search := func(from int) {
+		if from >= len(e.history.entries) {
+			from = len(e.history.entries) - 1
+		}
+		for idx := from; idx >= 0; idx-- {
+			if strings.Contains(e.history.entries[idx], string(query)) {
+				matchIdx, match = idx, e.history.entries[idx]
+				return
+			}
+		}
+	}
+
+	for {
+		fmt.Fprintf(e.out, "\r(reverse-i-search)`%s': %s\x1b[K", string(query), match)
+
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return keyCtrlD, false
+		}
+
+		switch {
+		case r == keyCtrlR:
+			search(matchIdx - 1)
+		case r == keyBackspace || r == keyCtrlH:
+			if len(query) > 0 {
+				query = query[:len(query)-1]
+				search(len(e.history.entries) - 1)
+			}
+		case r == keyCtrlG || r == keyCtrlC:
+			return 0, false
+		case r == keyEnter || r == '\n':
+			st.setLine([]rune(match))
+			return r, true
+		case unicode.IsPrint(r):
+			query = append(query, r)
+			search(matchIdx)
+		default:
+			st.setLine([]rune(match))
+			return r, false
+		}
+	}
+}
+
+// complete completes the word before the cursor. A unique match is filled
+// in; otherwise the common prefix is, and a second Tab lists the choices.
+func (e *editor) complete(st *lineState) {
+	start := st.pos
+	for start > 0 && isWordRune(st.buf[start-1]) {
+		start--
+	}
+
+	prefix := string(st.buf[start:st.pos])
+	if prefix == "" {
+		return
+	}
+
+	var candidates []string
+	seen := map[string]bool{}
+	for _, word := range e.completions() {
+		if strings.HasPrefix(word, prefix) && !seen[word] {
+			seen[word] = true
+			candidates = append(candidates, word)
+		}
+	}
+	sort.Strings(candidates)
+
+	if len(candidates) == 0 {
+		fmt.Fprint(e.out, "\a")
+		return
+	}
+
+	common := candidates[0]
+	for _, candidate := range candidates[1:] {
+		for !strings.HasPrefix(candidate, common) {
+			common = common[:len(common)-1]
+		}
+	}
+	for !utf8.ValidString(common) {
+		common = common[:len(common)-1]
+	}
+
+	if len(common) > len(prefix) {
+		insert := []rune(common[len(prefix):])
+		st.buf = append(st.buf[:st.pos], append(insert, st.buf[st.pos:]...)...)
+		st.pos += len(insert)
+		return
+	}
+
+	if len(candidates) > 1 {
+		fmt.Fprint(e.out, "\r\n"+strings.Join(candidates, "  ")+"\r\n")
+	}
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == ':'
+}
@@ -0,0 +1,73 @@
+package repl
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	historyFileName = ".sepia_history"
+	maxHistory      = 1000
+)
+
+// history is the list of lines entered at the REPL, oldest first, mirrored
+// to a file in the user's home directory so it survives between sessions.
+type history struct {
+	entries []string
+	file    string
+}
+
+func loadHistory() *history {
+	h := &history{}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return h
+	}
+	h.file = filepath.Join(home, historyFileName)
+
+	data, err := ioutil.ReadFile(h.file)
+	if err != nil {
+		return h
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) != "" {
+			h.entries = append(h.entries, line)
+		}
+	}
+	if len(h.entries) > maxHistory {
+		h.entries = h.entries[len(h.entries)-maxHistory:]
+	}
+
+	return h
+}
+
+// add records a line, skipping blank lines and immediate repeats.
+func (h *history) add(line string) {
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	if len(h.entries) > 0 && h.entries[len(h.entries)-1] == line {
+		return
+	}
+
+	h.entries = append(h.entries, line)
+	if len(h.entries) > maxHistory {
+		h.entries = h.entries[1:]
+	}
+
+	if h.file == "" {
+		return
+	}
+
+	// History is a convenience, so failing to save it is not worth interrupting the user.
+	f, err := os.OpenFile(h.file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	_, _ = f.WriteString(line + "\n")
+}
@@ -2,43 +2,180 @@ package repl
 
 import (
 	"bufio"
-	"fmt"
 	"io"
+	"os"
+	"sepia/ast"
 	"sepia/evaluator"
 	"sepia/lexer"
 	"sepia/objects"
 	"sepia/parser"
+	"sepia/token"
+	"strings"
 )
 
-const prompt string = "§ "
+const (
+	prompt             string = "§ "
+	continuationPrompt string = "… "
+)
+
+// session is the state of one REPL: where it writes, and the Interpreter and
+// Machine its input is evaluated with.
+type session struct {
+	out         io.Writer
+	interpreter *evaluator.Interpreter
+	machine     *objects.Machine
+}
 
-func Start(in io.Reader, out io.Writer) {
-	scanner := bufio.NewScanner(in)
-	machine := objects.NewMachine()
+func Start(in io.Reader, out io.Writer, interpreter *evaluator.Interpreter) {
+	s := &session{out: out, interpreter: interpreter, machine: objects.NewMachine()}
+	reader := s.lineReader(in)
 	for {
-		fmt.Print(prompt)
-		scanned := scanner.Scan()
-		if !scanned {
+		input, ok := readInput(reader)
+		if !ok {
 			return
 		}
-		line := scanner.Text()
-		l := lexer.New(line)
-		p := parser.New(l)
-		program := p.ParseProgram()
-		if len(p.Errors()) != 0 {
-			printParserErrors(out, p.Errors())
+
+		if strings.HasPrefix(strings.TrimSpace(input), ":") {
+			if !s.runCommand(strings.TrimSpace(input)) {
+				return
+			}
 			continue
 		}
 
-		evaluated := evaluator.Eval(program, machine)
+		s.eval(input, "")
+	}
+}
 
-		if evaluated != nil {
-			_, err := io.WriteString(out, evaluated.Inspect()+"\n")
-			check(err)
+// lineReader uses the line editor when in is an interactive terminal, and
+// reads plain lines otherwise.
+func (s *session) lineReader(in io.Reader) lineReader {
+	if f, ok := in.(*os.File); ok {
+		if t, ok := openTerminal(f); ok {
+			return &editor{
+				in:          bufio.NewReader(in),
+				out:         s.out,
+				terminal:    t,
+				history:     loadHistory(),
+				completions: s.completions,
+			}
 		}
+	}
 
+	return &scannerReader{scanner: bufio.NewScanner(in), out: s.out}
+}
+
+// completions lists keywords, builtins, meta-commands and the session's bindings.
+func (s *session) completions() []string {
+	words := append(token.Keywords(), evaluator.BuiltinNames()...)
+	words = append(words, s.machine.Names()...)
+	for _, cmd := range commands {
+		words = append(words, ":"+cmd.name)
+	}
+	return words
+}
+
+// eval parses and evaluates input in the session's Machine, printing the result.
+func (s *session) eval(input string, file string) {
+	program, ok := s.parse(input, file)
+	if !ok {
+		return
 	}
+
+	evaluated := s.interpreter.Run(program, s.machine)
+
+	if exit, ok := evaluated.(*objects.Exit); ok {
+		os.Exit(exit.Code)
+	}
+
+	if evaluated != nil {
+		_, err := io.WriteString(s.out, evaluated.Inspect()+"\n")
+		check(err)
+	}
+
+	if runtimeErr, ok := evaluated.(*objects.Error); ok {
+		_, err := io.WriteString(s.out, runtimeErr.StackTrace())
+		check(err)
+	}
+}
+
+func (s *session) parse(input string, file string) (*ast.Program, bool) {
+	p := parser.New(lexer.NewWithFile(input, file))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		printParserErrors(s.out, p.Errors())
+		return nil, false
+	}
+
+	return program, true
 }
+
+// readInput reads lines until they form a complete statement, showing a
+// continuation prompt while blocks, brackets or strings are still open.
+// Meta-commands are always a single line.
+// Ctrl-C abandons everything typed so far.
+func readInput(reader lineReader) (string, bool) {
+	var lines []string
+
+	for {
+		currentPrompt := prompt
+		if len(lines) > 0 {
+			currentPrompt = continuationPrompt
+		}
+
+		line, err := reader.ReadLine(currentPrompt)
+		if err == errInterrupted {
+			lines = nil
+			continue
+		} else if err != nil {
+			return "", false
+		}
+
+		lines = append(lines, line)
+		input := strings.Join(lines, "\n")
+
+		if strings.HasPrefix(strings.TrimSpace(input), ":") || !isIncomplete(input) {
+			return input, true
+		}
+	}
+}
+
+// isIncomplete reports whether input has unclosed `->` blocks, brackets,
+// braces, parentheses or strings. Surplus closers count as complete, so the
+// parser gets to report them.
+func isIncomplete(input string) bool {
+	depth := map[token.Type]int{}
+	closers := map[token.Type]token.Type{
+		token.CLOSEBLOCK: token.OPENBLOCK,
+		token.RPAREN:     token.LPAREN,
+		token.RBRACKET:   token.LBRACKET,
+		token.RBRACE:     token.LBRACE,
+
+		token.TEMPLATE_TAIL: token.TEMPLATE_HEAD,
+	}
+
+	l := lexer.New(input)
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		switch tok.Type {
+		case token.OPENBLOCK, token.LPAREN, token.LBRACKET, token.LBRACE, token.TEMPLATE_HEAD:
+			depth[tok.Type]++
+		case token.CLOSEBLOCK, token.RPAREN, token.RBRACKET, token.RBRACE, token.TEMPLATE_TAIL:
+			depth[closers[tok.Type]]--
+		case token.ILLEGAL:
+			if strings.HasPrefix(tok.Literal, "\"") || strings.HasPrefix(tok.Literal, "`") {
+				return true
+			}
+		}
+	}
+
+	for _, open := range depth {
+		if open > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
 func printParserErrors(out io.Writer, errors []string) {
 	for _, msg := range errors {
 		_, err := io.WriteString(out, "❌ PARSE ERROR: "+msg+"\n")
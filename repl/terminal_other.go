@@ -0,0 +1,14 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package repl
+
+import "os"
+
+// terminal is unsupported here; the REPL falls back to reading plain lines.
+type terminal struct{}
+
+func openTerminal(f *os.File) (*terminal, bool) { return nil, false }
+
+func (t *terminal) makeRaw() error { return nil }
+func (t *terminal) restore() error { return nil }
@@ -0,0 +1,50 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package repl
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// terminal switches a tty between its normal mode and the raw mode the line editor needs.
+type terminal struct {
+	fd       uintptr
+	original syscall.Termios
+}
+
+// openTerminal returns a terminal for f, or false if f isn't a tty.
+func openTerminal(f *os.File) (*terminal, bool) {
+	t := &terminal{fd: f.Fd()}
+	if err := t.ioctl(ioctlGetTermios, &t.original); err != nil {
+		return nil, false
+	}
+
+	return t, true
+}
+
+// makeRaw delivers keys one at a time, without echo or signals. Output
+// processing is left on, so "\n" still starts a new line.
+func (t *terminal) makeRaw() error {
+	raw := t.original
+	raw.Iflag &^= syscall.ICRNL | syscall.IXON | syscall.BRKINT | syscall.INPCK | syscall.ISTRIP
+	raw.Lflag &^= syscall.ECHO | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+
+	return t.ioctl(ioctlSetTermios, &raw)
+}
+
+func (t *terminal) restore() error {
+	return t.ioctl(ioctlSetTermios, &t.original)
+}
+
+func (t *terminal) ioctl(request uintptr, termios *syscall.Termios) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, t.fd, request, uintptr(unsafe.Pointer(termios)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
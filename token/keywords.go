@@ -1,20 +1,23 @@
 package token
 
 var keywords = map[string]Type{
-	"f":        FUNCTION,
-	"value":    VALUE,
-	"true":     TRUE,
-	"false":    FALSE,
-	"if":       IF,
-	"else":     ELSE,
-	"return":   RETURN,
-	"is":       EQ,
-	"not":      NOT_EQ,
-	"end":      CLOSEBLOCK,
-	"update":   UPDATE,
-	"constant": CONSTANT,
-	"and":      AND,
-	"or":       OR,
+	"f":         FUNCTION,
+	"value":     VALUE,
+	"true":      TRUE,
+	"false":     FALSE,
+	"if":        IF,
+	"else":      ELSE,
+	"return":    RETURN,
+	"is":        EQ,
+	"not":       NOT_EQ,
+	"end":       CLOSEBLOCK,
+	"update":    UPDATE,
+	"constant":  CONSTANT,
+	"and":       AND,
+	"or":        OR,
+	"import":    IMPORT,
+	"structure": STRUCTURE,
+	"match":     MATCH,
 }
 
 //LookupIdent finds an identifier token type from a string.
@@ -25,3 +28,12 @@ func LookupIdent(identifier string) Type {
 
 	return IDENT
 }
+
+// Keywords lists every reserved word.
+func Keywords() []string {
+	words := make([]string, 0, len(keywords))
+	for word := range keywords {
+		words = append(words, word)
+	}
+	return words
+}
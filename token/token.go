@@ -1,6 +1,7 @@
 package token
 
 type Token struct {
-	Type    Type
-	Literal string
+	Type     Type
+	Literal  string
+	Position Position
 }
@@ -26,21 +26,35 @@ const (
 	LBRACKET  = "["
 	RBRACKET  = "]"
 	COLON     = ":"
+	DOT       = "."
+	ELLIPSIS  = "..."
 	// Keywords
-	FUNCTION   = "FUNCTION"
-	VALUE      = "VALUE"
-	UPDATE     = "UPDATE"
-	CONSTANT   = "CONSTANT"
-	TRUE       = "TRUE"
-	FALSE      = "FALSE"
-	IF         = "IF"
-	ELSE       = "ELSE"
-	RETURN     = "RETURN"
-	STRING     = "STRING"
+	FUNCTION  = "FUNCTION"
+	VALUE     = "VALUE"
+	UPDATE    = "UPDATE"
+	CONSTANT  = "CONSTANT"
+	TRUE      = "TRUE"
+	FALSE     = "FALSE"
+	IF        = "IF"
+	ELSE      = "ELSE"
+	RETURN    = "RETURN"
+	IMPORT    = "IMPORT"
+	STRUCTURE = "STRUCTURE"
+	MATCH     = "MATCH"
+	STRING    = "STRING"
+
+	// An interpolated string is split around its `{expression}`s.
+	TEMPLATE_HEAD   = "TEMPLATE_HEAD"   // "text{
+	TEMPLATE_MIDDLE = "TEMPLATE_MIDDLE" // }text{
+	TEMPLATE_TAIL   = "TEMPLATE_TAIL"   // }text"
+
 	MINUS      = "-"
 	BANG       = "!"
 	ASTERISK   = "*"
 	SLASH      = "/"
+	FLOORDIV   = "//"
+	PERCENT    = "%"
+	POWER      = "**"
 	LT         = "<"
 	GT         = ">"
 	LTEQ       = "<="
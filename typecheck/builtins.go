@@ -0,0 +1,55 @@
+package typecheck
+
+import (
+	"sepia/evaluator"
+	"sepia/objects"
+)
+
+var convertible = []string{objects.STRING_OBJ, objects.INTEGER_OBJ, objects.FLOAT_OBJ, objects.BOOLEAN_OBJ}
+
+// builtinSignatures describes the builtins in evaluator/stdlib.go.
+var builtinSignatures = map[string]*function{
+	"len": {
+		params: []param{{types: []string{objects.STRING_OBJ, objects.ARRAY_OBJ}}},
+		min:    1, max: 1, result: integerType,
+	},
+	"typeof": {params: []param{{}}, min: 1, max: 1, result: stringType},
+	"print":  {min: 0, max: -1, result: nullType},
+	"exit": {
+		params: []param{{types: []string{objects.INTEGER_OBJ}}},
+		min:    0, max: 1, result: unknown,
+	},
+	"string": {params: []param{{types: convertible}}, min: 1, max: 1, result: stringType},
+	"bool":   {params: []param{{types: convertible}}, min: 1, max: 1, result: booleanType},
+	// int converts a STRING to its length in characters, not by parsing it.
+	"int":    {params: []param{{types: convertible}}, min: 1, max: 1, result: integerType},
+	"float":  {params: []param{{types: convertible}}, min: 1, max: 1, result: floatType},
+	"first":  {params: []param{{types: []string{objects.ARRAY_OBJ}}}, min: 1, max: 1, result: unknown},
+	"last":   {params: []param{{types: []string{objects.ARRAY_OBJ}}}, min: 1, max: 1, result: unknown},
+	"append": {params: []param{{types: []string{objects.ARRAY_OBJ}}, {}}, min: 2, max: 2, result: arrayType},
+	"rest":   {params: []param{{types: []string{objects.ARRAY_OBJ}}}, min: 1, max: 1, result: unknown},
+	"slice": {
+		params: []param{
+			{types: []string{objects.STRING_OBJ, objects.ARRAY_OBJ}},
+			{types: []string{objects.INTEGER_OBJ}},
+			{types: []string{objects.INTEGER_OBJ}},
+		},
+		min: 2, max: 3,
+		resultOf: func(args []Type) Type { return args[0] },
+	},
+}
+
+// builtins returns the type of every builtin function. Any without a
+// signature above can be called with anything.
+func builtins() map[string]Type {
+	types := make(map[string]Type)
+	for _, name := range evaluator.BuiltinNames() {
+		signature := function{max: -1, result: unknown}
+		if shared, ok := builtinSignatures[name]; ok {
+			signature = *shared // a copy, since every Check names its own
+		}
+		signature.name = name
+		types[name] = &signature
+	}
+	return types
+}
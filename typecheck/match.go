@@ -0,0 +1,93 @@
+package typecheck
+
+import (
+	"sepia/ast"
+	"sepia/objects"
+)
+
+// match checks a match expression, whose type is that of whichever arm's
+// body it evaluates.
+func (c *checker) match(node *ast.MatchExpression) Type {
+	subject := c.expression(node.Subject)
+
+	results := []Type{}
+	for _, arm := range node.Arms {
+		c.pattern(arm.Pattern, subject)
+		if arm.Guard != nil {
+			c.expression(arm.Guard)
+		}
+		results = append(results, c.block(arm.Body.Statements))
+	}
+	return unify(results...)
+}
+
+// pattern checks the names of the types, structures and fields a pattern
+// mentions, and gives the names it binds the types it guarantees them when
+// matching a value of type t.
+func (c *checker) pattern(pattern ast.Pattern, t Type) {
+	switch pattern := pattern.(type) {
+	case *ast.BindingPattern:
+		if pattern.Name.Type != nil && c.checkAnnotation(pattern.Name.Type) {
+			t = c.annotationType(pattern.Name.TypeName())
+		}
+		c.bindPattern(pattern.Name, t)
+	case *ast.ArrayPattern:
+		for _, element := range pattern.Elements {
+			c.pattern(element, unknown)
+		}
+		if pattern.Rest != nil {
+			c.bindPattern(pattern.Rest, arrayType)
+		}
+	case *ast.MapPattern:
+		for _, value := range pattern.Values {
+			c.pattern(value, unknown)
+		}
+	case *ast.StructPattern:
+		s := c.structure(pattern.Name)
+		for i, field := range pattern.Fields {
+			if s != nil && !s.hasField(field.Value) {
+				c.errorf(field, "structure `%s` has no field `%s`", s.name, field.Value)
+			}
+			c.pattern(pattern.Values[i], unknown)
+		}
+	}
+}
+
+// bindPattern gives name, bound by a pattern, the type t if it is bound only
+// once in its scope.
+func (c *checker) bindPattern(name *ast.Identifier, t Type) {
+	if name.Value == "_" {
+		return
+	}
+	if b, ok := c.scope.bindings[name.Value]; ok && b.stable && t != unknown {
+		b.typ = t
+	}
+}
+
+// structure finds the structure a struct pattern names, reporting names
+// that aren't structures. It returns nil if the structure isn't known.
+func (c *checker) structure(name *ast.Identifier) *structure {
+	if !c.structures[name.Value] {
+		c.errorf(name, "unknown structure `%s`", name.Value)
+		return nil
+	}
+	if b, ok := c.scope.lookup(name.Value); ok {
+		if s, ok := b.typ.(*structure); ok {
+			return s
+		}
+	}
+	return nil
+}
+
+// annotationType is the type of the values a type annotation allows.
+func (c *checker) annotationType(typeName string) Type {
+	if objects.IsBuiltinType(typeName) {
+		return basic(typeName)
+	}
+	if b, ok := c.scope.lookup(typeName); ok {
+		if s, ok := b.typ.(*structure); ok {
+			return &instance{structure: s}
+		}
+	}
+	return unknown
+}
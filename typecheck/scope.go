@@ -0,0 +1,81 @@
+package typecheck
+
+import "sepia/ast"
+
+// binding is what the checker knows about a name.
+type binding struct {
+	typ        Type   // the inferred type, if the binding is stable
+	annotation string // the type annotation every binding of the name in its scope agrees on
+	constant   bool
+
+	// stable is set if the name is bound once in its scope and never
+	// updated, so it keeps the type of the value it was bound to.
+	stable bool
+}
+
+// scope holds the bindings of the program or of a function body. Like a
+// Machine in the evaluator, it is shared by the blocks of any `if`s inside.
+type scope struct {
+	bindings map[string]*binding
+	outer    *scope
+}
+
+func newScope(outer *scope) *scope {
+	return &scope{bindings: make(map[string]*binding), outer: outer}
+}
+
+func (s *scope) lookup(name string) (*binding, bool) {
+	for scope := s; scope != nil; scope = scope.outer {
+		if b, ok := scope.bindings[name]; ok {
+			return b, true
+		}
+	}
+	return nil, false
+}
+
+// declare adds a binding for every name bound by statements, before any of
+// them are checked, so functions can refer to names bound after them.
+func (c *checker) declare(statements []ast.Statement) {
+	annotations := map[string][]string{}
+	constants := map[string]bool{}
+
+	for _, statement := range statements {
+		walk(statement, func(node ast.Node) bool {
+			switch node := node.(type) {
+			case *ast.FunctionLiteral:
+				return false // its body is a scope of its own
+			case *ast.ValueStatement:
+				annotations[node.Name.Value] = append(annotations[node.Name.Value], node.Name.TypeName())
+			case *ast.ConstantStatement:
+				annotations[node.Name.Value] = append(annotations[node.Name.Value], node.Name.TypeName())
+				constants[node.Name.Value] = true
+			case *ast.StructureStatement:
+				annotations[node.Name.Value] = append(annotations[node.Name.Value], "")
+				constants[node.Name.Value] = true
+			case *ast.MatchExpression:
+				for _, arm := range node.Arms {
+					for _, name := range ast.PatternBindings(arm.Pattern) {
+						annotations[name.Value] = append(annotations[name.Value], "")
+					}
+				}
+			}
+			return true
+		})
+	}
+
+	for name, types := range annotations {
+		annotation := types[0]
+		for _, t := range types[1:] {
+			if t != annotation {
+				annotation = ""
+			}
+		}
+
+		c.scope.bindings[name] = &binding{
+			typ:        unknown,
+			annotation: annotation,
+			constant:   constants[name],
+			stable:     len(types) == 1 && !c.updated[name],
+		}
+	}
+}
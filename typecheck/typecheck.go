@@ -0,0 +1,488 @@
+// Package typecheck finds type errors in a Sepia program without running it.
+//
+// It infers the types of literals, builtins, operators and functions, and
+// trusts type annotations. Anything it cannot pin down, like an unannotated
+// parameter, is unknown and goes with every type, so the errors it reports
+// are ones the program would hit at runtime if it got that far.
+package typecheck
+
+import (
+	"fmt"
+	"sepia/ast"
+	"sepia/evaluator"
+	"sepia/objects"
+	"sepia/token"
+	"sepia/util"
+	"sort"
+	"strings"
+)
+
+// Error is a type error found before running the program.
+type Error struct {
+	Position token.Position
+	Message  string
+}
+
+func (e *Error) Error() string {
+	if e.Position.IsValid() {
+		return e.Position.String() + ": " + e.Message
+	}
+	return e.Message
+}
+
+type checker struct {
+	scope  *scope
+	errors []*Error
+
+	updated    map[string]bool // names updated anywhere in the program
+	structures map[string]bool // names of the structures declared anywhere in the program
+
+	returns [][]Type // the types returned so far by each function being checked, innermost last
+
+	interpreter *evaluator.Interpreter // applies operators to sample operands
+}
+
+// Check returns the type errors in program, in source order.
+func Check(program *ast.Program) []*Error {
+	c := &checker{
+		scope:      &scope{bindings: map[string]*binding{}},
+		updated:    map[string]bool{},
+		structures: map[string]bool{},
+
+		interpreter: evaluator.New(),
+	}
+
+	for name, t := range builtins() {
+		c.scope.bindings[name] = &binding{typ: t, stable: true}
+	}
+
+	walk(program, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.UpdateStatement:
+			c.updated[node.Name.Value] = true
+		case *ast.AssignmentExpression:
+			c.updated[node.Name.Value] = true
+		case *ast.IncrementExpression:
+			c.updated[node.Name.Value] = true
+		case *ast.StructureStatement:
+			c.structures[node.Name.Value] = true
+		}
+		return true
+	})
+
+	c.scope = newScope(c.scope)
+	c.declare(program.Statements)
+	c.block(program.Statements)
+
+	sort.SliceStable(c.errors, func(i, j int) bool {
+		a, b := c.errors[i].Position, c.errors[j].Position
+		return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+	})
+	return c.errors
+}
+
+func (c *checker) errorf(node ast.Node, format string, a ...interface{}) {
+	c.errors = append(c.errors, &Error{Position: node.Pos(), Message: fmt.Sprintf(format, a...)})
+}
+
+//
+// CHECKING/STATEMENTS
+//
+
+// block checks statements in the current scope, returning the type of the
+// value they leave.
+func (c *checker) block(statements []ast.Statement) Type {
+	result := unknown
+	for _, statement := range statements {
+		result = c.statement(statement)
+	}
+	return result
+}
+
+func (c *checker) statement(statement ast.Statement) Type {
+	switch statement := statement.(type) {
+	case *ast.ExpressionStatement:
+		return c.expression(statement.Expression)
+	case *ast.ValueStatement:
+		c.bind(statement, statement.Name, statement.Value)
+	case *ast.ConstantStatement:
+		c.bind(statement, statement.Name, statement.Value)
+	case *ast.StructureStatement:
+		if objects.IsBuiltinType(statement.Name.Value) {
+			c.errorf(statement, "cannot name a structure `%s`, which is a builtin type", statement.Name.Value)
+		}
+
+		s := &structure{name: statement.Name.Value}
+		for _, field := range statement.Fields {
+			s.fields = append(s.fields, field.Value)
+		}
+		if b, ok := c.scope.bindings[s.name]; ok && b.stable {
+			b.typ = s
+		}
+	case *ast.UpdateStatement:
+		t := c.expression(statement.Value)
+		c.update(statement, statement.Name, t)
+	case *ast.ReturnStatement:
+		t := c.expression(statement.ReturnValue)
+		if len(c.returns) > 0 {
+			c.returns[len(c.returns)-1] = append(c.returns[len(c.returns)-1], t)
+		}
+		return t
+	}
+
+	return unknown
+}
+
+// bind checks a `value` or `constant` statement binding name to value.
+func (c *checker) bind(statement ast.Statement, name *ast.Identifier, value ast.Expression) {
+	var t Type
+	if function, ok := value.(*ast.FunctionLiteral); ok {
+		t = c.functionLiteral(function, name.Value)
+	} else {
+		t = c.expression(value)
+	}
+
+	if name.Type != nil && c.checkAnnotation(name.Type) {
+		if !accepts([]string{name.TypeName()}, t) {
+			c.errorf(statement, "`%s` must be %s, got %s", name.Value, name.TypeName(), t.Name())
+		}
+	}
+
+	if b, ok := c.scope.bindings[name.Value]; ok && b.stable && t != unknown {
+		b.typ = t
+	}
+}
+
+// update checks that name can be updated to a value of type t.
+func (c *checker) update(node ast.Node, name *ast.Identifier, t Type) {
+	b, ok := c.scope.lookup(name.Value)
+	if !ok {
+		c.errorf(node, "identifier not found: %s", name.Value)
+		return
+	}
+
+	if b.constant {
+		c.errorf(node, "Cannot update constant `%s`.", name.Value)
+	} else if b.annotation != "" && !accepts([]string{b.annotation}, t) {
+		c.errorf(node, "`%s` must be %s, got %s", name.Value, b.annotation, t.Name())
+	}
+}
+
+// checkAnnotation reports an annotation that names no type, returning
+// whether it names one.
+func (c *checker) checkAnnotation(annotation *ast.Identifier) bool {
+	if !objects.IsBuiltinType(annotation.Value) && !c.structures[annotation.Value] {
+		c.errorf(annotation, "unknown type `%s`", annotation.Value)
+		return false
+	}
+	return true
+}
+
+//
+// CHECKING/EXPRESSIONS
+//
+
+func (c *checker) expression(expression ast.Expression) Type {
+	switch node := expression.(type) {
+	case *ast.IntegerLiteral:
+		return integerType
+	case *ast.FloatLiteral:
+		return floatType
+	case *ast.StringLiteral:
+		return stringType
+	case *ast.BooleanLiteral:
+		return booleanType
+	case *ast.TemplateLiteral:
+		for _, part := range node.Parts {
+			t := c.expression(part)
+			if t.Name() != "" && !accepts(convertible, t) {
+				c.errorf(part, "cannot interpolate %s into a string", t.Name())
+			}
+		}
+		return stringType
+	case *ast.ArrayLiteral:
+		for _, element := range node.Elements {
+			c.expression(element)
+		}
+		return arrayType
+	case *ast.MapLiteral:
+		for key, value := range node.Pairs {
+			t := c.expression(key)
+			if t.Name() != "" && !accepts(convertible, t) {
+				c.errorf(key, "unusable as map key: %s", t.Name())
+			}
+			c.expression(value)
+		}
+		return mapType
+	case *ast.Identifier:
+		return c.identifier(node)
+	case *ast.PrefixExpression:
+		right := c.expression(node.Right)
+		return c.operation(node, func(operands ...objects.Object) objects.Object {
+			return c.interpreter.EvalPrefix(node.Operator, operands[0])
+		}, right)
+	case *ast.InfixExpression:
+		left := c.expression(node.Left)
+		right := c.expression(node.Right)
+		return c.infix(node, node.Operator, left, right)
+	case *ast.IfExpression:
+		c.expression(node.Condition)
+		consequence := c.block(node.Consequence.Statements)
+		if node.Alternative == nil {
+			return unknown
+		}
+		return unify(consequence, c.block(node.Alternative.Statements))
+	case *ast.MatchExpression:
+		return c.match(node)
+	case *ast.FunctionLiteral:
+		return c.functionLiteral(node, "")
+	case *ast.CallExpression:
+		return c.call(node)
+	case *ast.IndexExpression:
+		left := c.expression(node.Left)
+		index := c.expression(node.Index)
+		c.operation(node, func(operands ...objects.Object) objects.Object {
+			return evaluator.EvalIndex(operands[0], operands[1])
+		}, left, index)
+		return unknown
+	case *ast.MemberExpression:
+		return c.member(node)
+	case *ast.ImportExpression:
+		return moduleType
+	case *ast.AssignmentExpression:
+		value := c.expression(node.Value)
+		return c.assignment(node, node.Name, strings.TrimSuffix(node.Operator, "="), value)
+	case *ast.IncrementExpression:
+		return c.assignment(node, node.Name, node.Operator[:1], integerType)
+	}
+
+	return unknown
+}
+
+func (c *checker) identifier(node *ast.Identifier) Type {
+	b, ok := c.scope.lookup(node.Value)
+	if !ok {
+		c.errorf(node, "identifier not found: %s", node.Value)
+		return unknown
+	}
+
+	if b.typ != unknown {
+		return b.typ
+	}
+	if b.annotation != "" {
+		return c.named(b.annotation)
+	}
+	return unknown
+}
+
+// named is the type an annotation stands for.
+func (c *checker) named(annotation string) Type {
+	if b, ok := c.scope.lookup(annotation); ok {
+		if s, ok := b.typ.(*structure); ok {
+			return &instance{structure: s}
+		}
+	}
+
+	return basic(annotation)
+}
+
+func (c *checker) infix(node ast.Node, operator string, left, right Type) Type {
+	if operator == "==" || operator == "!=" {
+		return booleanType
+	}
+
+	result := c.operation(node, func(operands ...objects.Object) objects.Object {
+		return c.interpreter.EvalInfix(operator, operands[0], operands[1])
+	}, left, right)
+
+	if operator == "**" && result == integerType {
+		return unknown // a negative exponent makes a FLOAT
+	}
+	return result
+}
+
+// operation works out the type of applying an operator to operands of the
+// given types, by asking the evaluator to apply it to sample values of those
+// types, and reports the operator not supporting them. It gives up, with no
+// error, if any operand is of a type it has no sample for.
+func (c *checker) operation(node ast.Node, apply func(...objects.Object) objects.Object, operands ...Type) Type {
+	samples := make([]objects.Object, len(operands))
+	for i, t := range operands {
+		if samples[i] = sample(t); samples[i] == nil {
+			return unknown
+		}
+	}
+
+	result := apply(samples...)
+	if err, ok := result.(*objects.Error); ok {
+		if err.Kind == objects.TypeError {
+			c.errorf(node, "%s", err.Message)
+		}
+		return unknown
+	}
+
+	if result.Type() == objects.NULL_OBJ {
+		return unknown // an index out of range, which is not about types
+	}
+	return basic(result.Type())
+}
+
+// sample returns a value of type t, or nil if t is not a builtin type whose
+// values can be made up.
+func sample(t Type) objects.Object {
+	switch t {
+	case integerType:
+		return &objects.Integer{Value: 2}
+	case floatType:
+		return &objects.Float{Value: 2.5}
+	case stringType:
+		return &objects.String{Value: "s"}
+	case booleanType:
+		return evaluator.TRUE
+	case nullType:
+		return evaluator.NULL
+	case arrayType:
+		return &objects.Array{}
+	case mapType:
+		return &objects.Map{Pairs: map[objects.MapKey]objects.MapPair{}}
+	default:
+		return nil
+	}
+}
+
+func (c *checker) assignment(node ast.Node, name *ast.Identifier, operator string, value Type) Type {
+	t := c.infix(node, operator, c.identifier(name), value)
+	c.update(node, name, t)
+	return t
+}
+
+func (c *checker) member(node *ast.MemberExpression) Type {
+	object := c.expression(node.Object)
+
+	switch object := object.(type) {
+	case *instance:
+		for _, field := range object.structure.fields {
+			if field == node.Property.Value {
+				return unknown
+			}
+		}
+		c.errorf(node, "structure `%s` has no field `%s`", object.structure.name, node.Property.Value)
+		return unknown
+	}
+
+	name := object.Name()
+	if name != "" && name != objects.MODULE_OBJ && objects.IsBuiltinType(name) {
+		c.errorf(node, "member access not supported: %s.%s", name, node.Property.Value)
+	}
+	return unknown
+}
+
+func (c *checker) functionLiteral(node *ast.FunctionLiteral, name string) Type {
+	if name == "" {
+		name = "<anonymous>"
+	}
+	fn := &function{name: name, min: len(node.Parameters), max: len(node.Parameters), variadic: node.Variadic}
+	if node.Variadic {
+		fn.min, fn.max = len(node.Parameters)-1, -1
+	}
+
+	c.scope = newScope(c.scope)
+	defer func() { c.scope = c.scope.outer }()
+
+	for i, parameter := range node.Parameters {
+		p := param{name: parameter.Value}
+		known := parameter.Type != nil && c.checkAnnotation(parameter.Type)
+		if parameter.Type != nil {
+			p.types = []string{parameter.TypeName()}
+		}
+		fn.params = append(fn.params, p)
+
+		if node.Variadic && i == len(node.Parameters)-1 {
+			c.scope.bindings[parameter.Value] = &binding{typ: arrayType, stable: !c.updated[parameter.Value]}
+			continue
+		}
+
+		if i < len(node.Defaults) && node.Defaults[i] != nil {
+			if i < fn.min {
+				fn.min = i
+			}
+			// Defaults are worked out in the function's scope, after the parameters before them.
+			t := c.expression(node.Defaults[i])
+			if known && !accepts(p.types, t) {
+				c.errorf(node.Defaults[i], "parameter `%s` of `%s` must be %s, got %s", p.name, name, parameter.TypeName(), t.Name())
+			}
+		}
+
+		c.scope.bindings[parameter.Value] = &binding{
+			typ:        unknown,
+			annotation: parameter.TypeName(),
+			stable:     !c.updated[parameter.Value],
+		}
+	}
+	c.declare(node.Body.Statements)
+
+	c.returns = append(c.returns, nil)
+	last := c.block(node.Body.Statements)
+	returns := c.returns[len(c.returns)-1]
+	c.returns = c.returns[:len(c.returns)-1]
+
+	fn.result = unify(append(returns, last)...)
+	return fn
+}
+
+func (c *checker) call(node *ast.CallExpression) Type {
+	callee := c.expression(node.Function)
+	args := make([]Type, len(node.Arguments))
+	for i, arg := range node.Arguments {
+		args[i] = c.expression(arg)
+	}
+
+	switch callee := callee.(type) {
+	case *function:
+		return c.apply(node, callee, args)
+	case *structure:
+		if len(args) != len(callee.fields) {
+			c.errorf(node, "structure `%s` expects %s, got %d", callee.name, util.Plural(len(callee.fields), "field"), len(args))
+		}
+		return &instance{structure: callee}
+	}
+
+	switch callee.Name() {
+	case "", objects.FUNCTION_OBJ, objects.BUILTIN_OBJ, objects.STRUCTURE_OBJ:
+		return unknown
+	default:
+		c.errorf(node, "not a function: %s", callee.Name())
+		return unknown
+	}
+}
+
+// apply checks the arguments of a call to fn and returns the type of its result.
+func (c *checker) apply(node *ast.CallExpression, fn *function, args []Type) Type {
+	if len(args) < fn.min || fn.max >= 0 && len(args) > fn.max {
+		c.errorf(node, "%s", objects.ArityError(fn.name, fn.min, fn.max, len(args)).Message)
+		return unknown
+	}
+
+	for i := range args {
+		p, ok := fn.param(i)
+		if !ok || accepts(p.types, args[i]) {
+			continue
+		}
+
+		switch {
+		case fn.variadic && i >= len(fn.params)-1:
+			c.errorf(node.Arguments[i], "each of parameter `%s` of `%s` must be %s, got %s", p.name, fn.name, p.types[0], args[i].Name())
+		case p.name != "":
+			c.errorf(node.Arguments[i], "parameter `%s` of `%s` must be %s, got %s", p.name, fn.name, p.types[0], args[i].Name())
+		case len(fn.params) == 1:
+			c.errorf(node.Arguments[i], "argument to `%s` must be %s, got %s", fn.name, strings.Join(p.types, " or "), args[i].Name())
+		default:
+			c.errorf(node.Arguments[i], "argument %d to `%s` must be %s, got %s", i+1, fn.name, strings.Join(p.types, " or "), args[i].Name())
+		}
+	}
+
+	if fn.resultOf != nil {
+		return fn.resultOf(args)
+	}
+	return fn.result
+}
@@ -0,0 +1,158 @@
+package typecheck
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"sepia/ast"
+	"sepia/lexer"
+	"sepia/parser"
+	"sync"
+	"testing"
+)
+
+func parse(t *testing.T, file, source string) *ast.Program {
+	p := parser.New(lexer.NewWithFile(source, file))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parse errors in %s: %v", file, p.Errors())
+	}
+	return program
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   []string
+	}{
+		// Errors the evaluator would report when running the program.
+		{"type mismatch", `"a" + 1`, []string{
+			"1:5: type mismatch: STRING + INTEGER",
+		}},
+		{"unknown operator", `-"a"`, []string{
+			"1:1: unknown operator: -STRING",
+		}},
+		{"calling a non-function", "value n = 1\nn()", []string{
+			"2:2: not a function: INTEGER",
+		}},
+		{"too few arguments", "value add = f(a, b) -> a + b end\nadd(1)", []string{
+			"2:4: `add` expects 2 arguments, got 1",
+		}},
+		{"too many arguments to a builtin", `len("a", "b")`, []string{
+			"1:4: `len` expects 1 argument, got 2",
+		}},
+		{"builtin argument", `len(1)`, []string{
+			"1:5: argument to `len` must be STRING or ARRAY, got INTEGER",
+		}},
+		{"parameter annotation", "value double = f(n: INTEGER) -> n * 2 end\ndouble(\"two\")", []string{
+			"2:8: parameter `n` of `double` must be INTEGER, got STRING",
+		}},
+		{"value annotation", `value n: INTEGER = "one"`, []string{
+			"1:1: `n` must be INTEGER, got STRING",
+		}},
+		{"unknown type", `value n: NUMBER = 1`, []string{
+			"1:10: unknown type `NUMBER`",
+		}},
+		{"updating a constant", "constant n = 1\nupdate n = 2", []string{
+			"2:1: Cannot update constant `n`.",
+		}},
+		{"unknown identifier", `print(m)`, []string{
+			"1:7: identifier not found: m",
+		}},
+		{"structure arity", "structure Point -> x, y end\nPoint(1)", []string{
+			"2:6: structure `Point` expects 2 fields, got 1",
+		}},
+		{"structure field", "structure Point -> x, y end\nvalue p = Point(1, 2)\np.z", []string{
+			"3:2: structure `Point` has no field `z`",
+		}},
+		{"structure pattern field", "structure Point -> x, y end\nmatch (Point(1, 2)) -> Point { z } -> z end end", []string{
+			"2:32: structure `Point` has no field `z`",
+		}},
+		{"errors in source order", "len(1)\n\"a\" - \"b\"", []string{
+			"1:5: argument to `len` must be STRING or ARRAY, got INTEGER",
+			"2:5: unknown operator: STRING - STRING",
+		}},
+
+		// Programs that run without errors must check clean.
+		{"redefinition with a new type", "value x = 1\nvalue x = \"one\"\nprint(x + \"!\")", nil},
+		{"update to another type", "value x = 1\nupdate x = \"one\"\nprint(x + \"!\")", nil},
+		{"recursion", `
+value factorial = f(n) ->
+    if (n < 2) -> 1 end else -> n * factorial(n - 1) end
+end
+print(factorial(5) + 1)
+`, nil},
+		{"forward reference", `
+value isEven = f(n) ->
+    if (n == 0) -> true end else -> isOdd(n - 1) end
+end
+value isOdd = f(n) ->
+    if (n == 0) -> false end else -> isEven(n - 1) end
+end
+print(isEven(4))
+`, nil},
+		{"binding in a guarded match", `
+value describe = f(x) ->
+    match (x) ->
+        n: INTEGER if (n < 0) -> "negative " + string(n) end
+        s: STRING -> s + "!" end
+        _ -> "other" end
+    end
+end
+print(describe(-1))
+`, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errors := Check(parse(t, "", tt.source))
+
+			got := []string{}
+			for _, err := range errors {
+				got = append(got, err.Error())
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Check returned %d errors, want %d:\n%q", len(got), len(tt.want), got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("error %d = %q, want %q", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// TestExamplesCheckClean checks every example, none of which fails when run.
+func TestExamplesCheckClean(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("..", "examples", "*.sp"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range files {
+		source, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, err := range Check(parse(t, file, string(source))) {
+			t.Errorf("%s", err)
+		}
+	}
+}
+
+// TestCheckConcurrently runs several checks at once, which share the builtin
+// signatures but must not write to them.
+func TestCheckConcurrently(t *testing.T) {
+	program := parse(t, "", `len(1)`)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			Check(program)
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,123 @@
+package typecheck
+
+import "sepia/objects"
+
+// Type is what the checker knows about the values an expression can produce.
+type Type interface {
+	// Name is the type `typeof` would report, or "" if it is not known.
+	Name() string
+}
+
+// basic is one of the builtin object types.
+type basic string
+
+func (b basic) Name() string { return string(b) }
+
+// unknown is the type of anything the checker cannot pin down. It goes with
+// every other type, so it never causes an error.
+var unknown Type = basic("")
+
+var (
+	integerType = basic(objects.INTEGER_OBJ)
+	floatType   = basic(objects.FLOAT_OBJ)
+	stringType  = basic(objects.STRING_OBJ)
+	booleanType = basic(objects.BOOLEAN_OBJ)
+	nullType    = basic(objects.NULL_OBJ)
+	arrayType   = basic(objects.ARRAY_OBJ)
+	mapType     = basic(objects.MAP_OBJ)
+	moduleType  = basic(objects.MODULE_OBJ)
+)
+
+// function is a function with a known signature.
+type function struct {
+	name     string
+	params   []param
+	min, max int  // how many arguments it takes; max < 0 for any number
+	variadic bool // whether the last of params takes every further argument
+	result   Type
+
+	// resultOf works out the result of a builtin whose result depends on its
+	// arguments. It is used instead of result if set.
+	resultOf func(args []Type) Type
+}
+
+func (f *function) Name() string { return objects.FUNCTION_OBJ }
+
+// param returns the parameter taking the i-th argument, if there is one.
+func (f *function) param(i int) (param, bool) {
+	if f.variadic && i >= len(f.params)-1 {
+		return f.params[len(f.params)-1], true
+	}
+	if i < len(f.params) {
+		return f.params[i], true
+	}
+	return param{}, false
+}
+
+// param is a parameter and the types it accepts, none meaning any.
+type param struct {
+	name  string
+	types []string
+}
+
+// structure is a declared structure, which is called to make an instance.
+type structure struct {
+	name   string
+	fields []string
+}
+
+func (s *structure) Name() string { return objects.STRUCTURE_OBJ }
+
+func (s *structure) hasField(name string) bool {
+	for _, field := range s.fields {
+		if field == name {
+			return true
+		}
+	}
+	return false
+}
+
+// instance is a struct made by calling a structure.
+type instance struct {
+	structure *structure
+}
+
+func (i *instance) Name() string { return i.structure.name }
+
+// accepts reports whether a value of type t may be used where one of the
+// named types is expected.
+func accepts(names []string, t Type) bool {
+	if t.Name() == "" || len(names) == 0 {
+		return true
+	}
+
+	for _, name := range names {
+		if t.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// unify is the type of a value that may come from any of types.
+func unify(types ...Type) Type {
+	if len(types) == 0 {
+		return unknown
+	}
+
+	first := types[0]
+	for _, t := range types[1:] {
+		if t.Name() != first.Name() {
+			return unknown
+		}
+
+		// Functions and structures are only the same if they are the same one.
+		switch t.(type) {
+		case *function, *structure:
+			if t != first {
+				return unknown
+			}
+		}
+	}
+	return first
+}
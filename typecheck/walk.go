@@ -0,0 +1,96 @@
+package typecheck
+
+import "sepia/ast"
+
+// walk calls visit on node and then, if visit returns true, walks each of
+// node's children in turn.
+func walk(node ast.Node, visit func(ast.Node) bool) {
+	if node == nil || !visit(node) {
+		return
+	}
+
+	for _, child := range children(node) {
+		walk(child, visit)
+	}
+}
+
+func children(node ast.Node) []ast.Node {
+	nodes := []ast.Node{}
+	add := func(children ...ast.Node) {
+		for _, child := range children {
+			if child != nil {
+				nodes = append(nodes, child)
+			}
+		}
+	}
+
+	switch node := node.(type) {
+	case *ast.Program:
+		for _, statement := range node.Statements {
+			add(statement)
+		}
+	case *ast.BlockStatement:
+		for _, statement := range node.Statements {
+			add(statement)
+		}
+	case *ast.ExpressionStatement:
+		add(node.Expression)
+	case *ast.ValueStatement:
+		add(node.Value)
+	case *ast.ConstantStatement:
+		add(node.Value)
+	case *ast.UpdateStatement:
+		add(node.Value)
+	case *ast.ReturnStatement:
+		add(node.ReturnValue)
+	case *ast.PrefixExpression:
+		add(node.Right)
+	case *ast.InfixExpression:
+		add(node.Left, node.Right)
+	case *ast.IfExpression:
+		add(node.Condition, node.Consequence)
+		if node.Alternative != nil {
+			add(node.Alternative)
+		}
+	case *ast.MatchExpression:
+		add(node.Subject)
+		for _, arm := range node.Arms {
+			if arm.Guard != nil {
+				add(arm.Guard)
+			}
+			add(arm.Body)
+		}
+	case *ast.FunctionLiteral:
+		for _, value := range node.Defaults {
+			if value != nil {
+				add(value)
+			}
+		}
+		add(node.Body)
+	case *ast.CallExpression:
+		add(node.Function)
+		for _, arg := range node.Arguments {
+			add(arg)
+		}
+	case *ast.TemplateLiteral:
+		for _, part := range node.Parts {
+			add(part)
+		}
+	case *ast.ArrayLiteral:
+		for _, element := range node.Elements {
+			add(element)
+		}
+	case *ast.MapLiteral:
+		for key, value := range node.Pairs {
+			add(key, value)
+		}
+	case *ast.IndexExpression:
+		add(node.Left, node.Index)
+	case *ast.MemberExpression:
+		add(node.Object)
+	case *ast.AssignmentExpression:
+		add(node.Value)
+	}
+
+	return nodes
+}
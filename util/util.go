@@ -1,15 +1,32 @@
 package util
 
-//IsLetter returns if a character is a letter.
-func IsLetter(ch byte) bool {
-	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+import (
+	"fmt"
+	"unicode"
+)
+
+//IsLetter returns if a character is a letter, in any script, or an underscore.
+func IsLetter(ch rune) bool {
+	return unicode.IsLetter(ch) || ch == '_'
 }
 
 //IsWhitespace returns if a character is whitespace or not.
-func IsWhitespace(ch byte) bool {
+func IsWhitespace(ch rune) bool {
 	return ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r'
 }
 
-func IsDigit(ch byte) bool {
+func IsDigit(ch rune) bool {
 	return '0' <= ch && ch <= '9'
 }
+
+func IsHexDigit(ch rune) bool {
+	return IsDigit(ch) || 'a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F'
+}
+
+//Plural counts n of something, as in "1 field" or "2 fields".
+func Plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
@@ -0,0 +1,426 @@
+package vm
+
+import (
+	"fmt"
+	"sepia/compiler"
+	"sepia/evaluator"
+	"sepia/objects"
+	"sepia/token"
+)
+
+const initialStackSize = 2048
+
+func newError(format string, a ...interface{}) *objects.Error {
+	return &objects.Error{Message: fmt.Sprintf(format, a...)}
+}
+
+// Closure is a compiled function together with the environment it was created in.
+type Closure struct {
+	Fn  *compiler.CompiledFunction
+	Env *Env
+}
+
+func (c *Closure) Type() objects.ObjectType { return objects.FUNCTION_OBJ }
+func (c *Closure) Inspect() string          { return "fn " + c.Fn.Name }
+
+// Env holds the bindings of one scope in slots assigned by the compiler.
+type Env struct {
+	slots []objects.Object
+	names []string
+	outer *Env
+}
+
+func newEnv(fn *compiler.CompiledFunction, outer *Env) *Env {
+	return &Env{slots: make([]objects.Object, len(fn.SlotNames)), names: fn.SlotNames, outer: outer}
+}
+
+func (e *Env) at(depth int) *Env {
+	env := e
+	for ; depth > 0; depth-- {
+		env = env.outer
+	}
+	return env
+}
+
+type frame struct {
+	closure  *Closure
+	env      *Env
+	ip       int
+	base     int // stack pointer when the frame was entered
+	callSite token.Position
+	module   bool // whether the frame runs an imported file, returning its bindings
+}
+
+// VM runs compiled Sepia bytecode.
+type VM struct {
+	constants []objects.Object
+
+	stack []objects.Object
+	sp    int
+
+	frames []*frame
+
+	modules map[*compiler.CompiledFunction]*objects.Module // every module imported so far
+
+	// interpreter gives operators and patterns their meaning, and sets the
+	// maximum call depth and whether arithmetic is checked.
+	interpreter *evaluator.Interpreter
+}
+
+func New(bytecode *compiler.Bytecode, interpreter *evaluator.Interpreter) *VM {
+	main := &Closure{Fn: bytecode.Main}
+	main.Env = newEnv(bytecode.Main, nil)
+
+	return &VM{
+		constants: bytecode.Constants,
+		stack:     make([]objects.Object, initialStackSize),
+		frames:    []*frame{{closure: main, env: main.Env}},
+		modules:   map[*compiler.CompiledFunction]*objects.Module{},
+
+		interpreter: interpreter,
+	}
+}
+
+func (vm *VM) push(obj objects.Object) {
+	if vm.sp >= len(vm.stack) {
+		vm.stack = append(vm.stack, make([]objects.Object, len(vm.stack))...)
+	}
+
+	vm.stack[vm.sp] = obj
+	vm.sp++
+}
+
+func (vm *VM) pop() objects.Object {
+	vm.sp--
+	obj := vm.stack[vm.sp]
+	vm.stack[vm.sp] = nil
+	return obj
+}
+
+// Run executes the program, returning the value of its last expression, an
+// *objects.Error if it failed, or an *objects.Exit if it called `exit`. A Go
+// panic while running is reported as an internal error.
+func (vm *VM) Run() (result objects.Object) {
+	defer func() {
+		if r := recover(); r != nil {
+			f := vm.frames[len(vm.frames)-1]
+			result = vm.fail(newError("internal error: %v", r), f.ip)
+		}
+	}()
+
+	return vm.run()
+}
+
+func (vm *VM) run() objects.Object {
+	for {
+		f := vm.frames[len(vm.frames)-1]
+		ins := f.closure.Fn.Instructions
+		ip := f.ip
+		op := compiler.Opcode(ins[ip])
+
+		var result objects.Object
+
+		switch op {
+		case compiler.OpConstant:
+			f.ip += 3
+			vm.push(vm.constants[compiler.ReadUint16(ins[ip+1:])])
+		case compiler.OpPop:
+			f.ip++
+			vm.pop()
+		case compiler.OpTrue:
+			f.ip++
+			vm.push(evaluator.TRUE)
+		case compiler.OpFalse:
+			f.ip++
+			vm.push(evaluator.FALSE)
+		case compiler.OpNull:
+			f.ip++
+			vm.push(evaluator.NULL)
+		case compiler.OpInfix:
+			f.ip += 3
+			operator := vm.constants[compiler.ReadUint16(ins[ip+1:])].(*objects.String).Value
+			left := vm.pop()
+			right := vm.pop()
+			result = vm.interpreter.EvalInfix(operator, left, right)
+		case compiler.OpPrefix:
+			f.ip += 3
+			operator := vm.constants[compiler.ReadUint16(ins[ip+1:])].(*objects.String).Value
+			result = vm.interpreter.EvalPrefix(operator, vm.pop())
+		case compiler.OpJump:
+			f.ip = int(compiler.ReadUint16(ins[ip+1:]))
+		case compiler.OpJumpNotTruthy:
+			if evaluator.IsTruthy(vm.pop()) {
+				f.ip += 3
+			} else {
+				f.ip = int(compiler.ReadUint16(ins[ip+1:]))
+			}
+		case compiler.OpJumpIfSet:
+			if f.env.slots[compiler.ReadUint16(ins[ip+3:])] != nil {
+				f.ip = int(compiler.ReadUint16(ins[ip+1:]))
+			} else {
+				f.ip += 5
+			}
+		case compiler.OpMatch:
+			pattern := vm.constants[compiler.ReadUint16(ins[ip+1:])].(*compiler.Pattern).Pattern
+			values, matched := vm.interpreter.MatchPattern(pattern, vm.stack[vm.sp-1])
+			if matched.Type() == objects.ERROR_OBJ {
+				return vm.fail(matched, ip)
+			}
+			if matched != evaluator.TRUE {
+				f.ip = int(compiler.ReadUint16(ins[ip+3:]))
+				break
+			}
+			f.ip += 5
+			for _, value := range values {
+				vm.push(value)
+			}
+		case compiler.OpNoMatch:
+			return vm.fail(evaluator.NoMatch(vm.pop()), ip)
+		case compiler.OpGetVar:
+			f.ip += 4
+			env := f.env.at(int(ins[ip+1]))
+			slot := compiler.ReadUint16(ins[ip+2:])
+			if val := env.slots[slot]; val != nil {
+				vm.push(val)
+			} else if builtin, ok := evaluator.LookupBuiltin(env.names[slot]); ok {
+				// A name that will shadow a builtin still means the builtin until it's bound.
+				vm.push(builtin)
+			} else {
+				return vm.fail(newError("identifier not found: "+env.names[slot]), ip)
+			}
+		case compiler.OpSetVar:
+			f.ip += 4
+			env := f.env.at(int(ins[ip+1]))
+			env.slots[compiler.ReadUint16(ins[ip+2:])] = vm.pop()
+		case compiler.OpCheckType:
+			f.ip += 5
+			typeName := vm.constants[compiler.ReadUint16(ins[ip+1:])].(*objects.String).Value
+			what := vm.constants[compiler.ReadUint16(ins[ip+3:])].(*objects.String).Value
+			if err := objects.CheckType(what, typeName, vm.stack[vm.sp-1]); err != nil {
+				return vm.fail(err, ip)
+			}
+		case compiler.OpUndefined:
+			name := vm.constants[compiler.ReadUint16(ins[ip+1:])].(*objects.String).Value
+			return vm.fail(newError("identifier not found: "+name), ip)
+		case compiler.OpFail:
+			message := vm.constants[compiler.ReadUint16(ins[ip+1:])].(*objects.String).Value
+			return vm.fail(newError("%s", message), ip)
+		case compiler.OpArray:
+			f.ip += 3
+			count := int(compiler.ReadUint16(ins[ip+1:]))
+			elements := make([]objects.Object, count)
+			copy(elements, vm.stack[vm.sp-count:vm.sp])
+			vm.sp -= count
+			result = &objects.Array{Elements: elements}
+		case compiler.OpTemplate:
+			f.ip += 3
+			count := int(compiler.ReadUint16(ins[ip+1:]))
+			parts := make([]objects.Object, count)
+			copy(parts, vm.stack[vm.sp-count:vm.sp])
+			vm.sp -= count
+			result = evaluator.EvalTemplate(parts)
+		case compiler.OpMap:
+			f.ip += 3
+			count := int(compiler.ReadUint16(ins[ip+1:]))
+			result = vm.buildMap(count)
+		case compiler.OpIndex:
+			f.ip++
+			index := vm.pop()
+			left := vm.pop()
+			result = evaluator.EvalIndex(left, index)
+		case compiler.OpMember:
+			f.ip += 3
+			property := vm.constants[compiler.ReadUint16(ins[ip+1:])].(*objects.String).Value
+			result = evaluator.EvalMember(vm.pop(), property)
+		case compiler.OpClosure:
+			f.ip += 3
+			fn := vm.constants[compiler.ReadUint16(ins[ip+1:])].(*compiler.CompiledFunction)
+			vm.push(&Closure{Fn: fn, Env: f.env})
+		case compiler.OpImport:
+			f.ip += 3
+			fn := vm.constants[compiler.ReadUint16(ins[ip+1:])].(*compiler.CompiledFunction)
+			if module, ok := vm.modules[fn]; ok {
+				vm.push(module)
+				break
+			}
+			module := &Closure{Fn: fn}
+			module.Env = newEnv(fn, nil)
+			vm.frames = append(vm.frames, &frame{closure: module, env: module.Env, base: vm.sp, callSite: f.closure.Fn.Positions[ip], module: true})
+		case compiler.OpCall, compiler.OpTailCall:
+			f.ip += 2
+			if err := vm.call(int(ins[ip+1]), f.closure.Fn.Positions[ip], op == compiler.OpTailCall); err != nil {
+				return vm.fail(err, ip)
+			}
+		case compiler.OpReturnValue:
+			returnValue := vm.pop()
+			if len(vm.frames) == 1 {
+				return returnValue
+			}
+
+			vm.frames = vm.frames[:len(vm.frames)-1]
+			vm.sp = f.base
+			if f.module {
+				returnValue = vm.newModule(f.closure)
+			}
+			vm.push(returnValue)
+		default:
+			return vm.fail(newError("unknown opcode %d", op), ip)
+		}
+
+		if result != nil {
+			if result.Type() == objects.ERROR_OBJ || result.Type() == objects.EXIT_OBJ {
+				return vm.fail(result, ip)
+			}
+			vm.push(result)
+		}
+	}
+}
+
+// call applies the callee sitting below argCount arguments on the stack. A
+// tail call to a closure replaces the current frame instead of adding one.
+func (vm *VM) call(argCount int, callSite token.Position, isTail bool) objects.Object {
+	callee := vm.stack[vm.sp-1-argCount]
+
+	switch callee := callee.(type) {
+	case *Closure:
+		fixed, max := callee.Fn.NumParameters, callee.Fn.NumParameters
+		if callee.Fn.Variadic {
+			fixed, max = fixed-1, -1
+		}
+		if argCount < callee.Fn.NumRequired || max >= 0 && argCount > max {
+			return objects.ArityError(callee.Fn.Name, callee.Fn.NumRequired, max, argCount)
+		}
+
+		args := vm.stack[vm.sp-argCount : vm.sp]
+		for i, arg := range args {
+			typeName := ""
+			if i < fixed {
+				typeName = callee.Fn.ParameterTypes[i]
+			} else {
+				typeName = callee.Fn.ParameterTypes[fixed]
+			}
+			if typeName == "" || objects.HasType(arg, typeName) {
+				continue
+			}
+			what := ""
+			if i < fixed {
+				what = fmt.Sprintf("parameter `%s` of `%s`", callee.Fn.SlotNames[i], callee.Fn.Name)
+			} else {
+				what = fmt.Sprintf("each of parameter `%s` of `%s`", callee.Fn.SlotNames[fixed], callee.Fn.Name)
+			}
+			return objects.CheckType(what, typeName, arg)
+		}
+
+		env := newEnv(callee.Fn, callee.Env)
+		if argCount > fixed {
+			copy(env.slots, args[:fixed])
+		} else {
+			copy(env.slots, args)
+		}
+		if callee.Fn.Variadic {
+			rest := []objects.Object{}
+			if argCount > fixed {
+				rest = append(rest, args[fixed:]...)
+			}
+			env.slots[fixed] = &objects.Array{Elements: rest}
+		}
+
+		// A module's frame must stay to turn its bindings into the module.
+		if isTail && !vm.frames[len(vm.frames)-1].module {
+			f := vm.frames[len(vm.frames)-1]
+			vm.sp = f.base
+			f.closure, f.env, f.ip, f.callSite = callee, env, 0, callSite
+			return nil
+		}
+
+		if vm.interpreter.MaxCallDepth > 0 && len(vm.frames)-1 >= vm.interpreter.MaxCallDepth {
+			return newError("maximum call depth %d exceeded in `%s`", vm.interpreter.MaxCallDepth, callee.Fn.Name)
+		}
+
+		vm.sp -= argCount + 1
+		vm.frames = append(vm.frames, &frame{closure: callee, env: env, base: vm.sp, callSite: callSite})
+		return nil
+	case *objects.Builtin, *objects.Structure:
+		args := make([]objects.Object, argCount)
+		copy(args, vm.stack[vm.sp-argCount:vm.sp])
+		vm.sp -= argCount + 1
+
+		var result objects.Object
+		if structure, ok := callee.(*objects.Structure); ok {
+			result = evaluator.Instantiate(structure, args)
+		} else {
+			result = callee.(*objects.Builtin).Fn(args...)
+		}
+		if result == nil {
+			result = evaluator.NULL
+		}
+		if result.Type() == objects.ERROR_OBJ || result.Type() == objects.EXIT_OBJ {
+			return result
+		}
+
+		vm.push(result)
+		return nil
+	default:
+		return newError("not a function: %s", callee.Type())
+	}
+}
+
+// newModule makes the Module for an imported file that has finished running,
+// from the bindings left in its environment.
+func (vm *VM) newModule(closure *Closure) *objects.Module {
+	module := &objects.Module{Path: closure.Fn.Name, Machine: objects.NewMachine()}
+	for slot, val := range closure.Env.slots {
+		if val != nil {
+			module.Machine.Set(closure.Env.names[slot], val)
+		}
+	}
+
+	vm.modules[closure.Fn] = module
+	return module
+}
+
+func (vm *VM) buildMap(count int) objects.Object {
+	pairs := make(map[objects.MapKey]objects.MapPair)
+
+	for i := vm.sp - 2*count; i < vm.sp; i += 2 {
+		key := vm.stack[i]
+		value := vm.stack[i+1]
+
+		mapKey, ok := key.(objects.Mappable)
+		if !ok {
+			err := newError("unusable as map key: %s", key.Type())
+			err.Kind = objects.TypeError
+			return err
+		}
+
+		pairs[mapKey.MapKey()] = objects.MapPair{Key: key, Value: value}
+	}
+
+	vm.sp -= 2 * count
+	return &objects.Map{Pairs: pairs}
+}
+
+// fail stops the program with result. Errors are given the position of the
+// failing instruction and a trace of the active calls.
+func (vm *VM) fail(result objects.Object, ip int) objects.Object {
+	err, ok := result.(*objects.Error)
+	if !ok {
+		return result
+	}
+
+	f := vm.frames[len(vm.frames)-1]
+	if !err.Position.IsValid() {
+		err.Position = f.closure.Fn.Positions[ip]
+	}
+
+	if err.Trace == nil {
+		for _, f := range vm.frames[1:] {
+			if f.module {
+				continue // imports aren't calls, as in the evaluator
+			}
+			err.Trace = append(err.Trace, objects.Frame{Function: f.closure.Fn.Name, CallSite: f.callSite})
+		}
+	}
+
+	return err
+}